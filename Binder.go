@@ -0,0 +1,172 @@
+package goioc
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Bind 将配置绑定到结构体，target 必须是结构体指针。
+// 字段对应的键默认为字段名称，可以通过 `config:"name"` tag 修改，`config:"-"` 表示忽略该字段；
+// 配置中不存在的字段使用 `default:"..."` tag 的值；
+// 所有字段的错误会通过 *AggregateError 一起返回。
+func Bind(configuration IConfiguration, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("[ %T ] is not a struct pointer", target)
+	}
+	var errs []error
+	bindStruct(configuration, v.Elem(), v.Elem().Type().Name(), &errs)
+	if len(errs) > 0 {
+		return &AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+func bindStruct(configuration IConfiguration, v reflect.Value, path string, errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := field.Name
+		if name, ok := field.Tag.Lookup("config"); ok {
+			if name == "-" {
+				continue
+			}
+			key = name
+		}
+		fieldPath := path + "." + field.Name
+		fv := v.Field(i)
+
+		// 嵌套的结构体使用子配置绑定
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr && fieldType.Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fieldType.Elem()))
+			}
+			bindStruct(configuration.GetSection(key), fv.Elem(), fieldPath, errs)
+			continue
+		}
+		if fieldType.Kind() == reflect.Struct {
+			bindStruct(configuration.GetSection(key), fv, fieldPath, errs)
+			continue
+		}
+
+		if err := BindField(configuration, key, fv, field.Tag); err != nil {
+			*errs = append(*errs, fmt.Errorf("bind [ %s ]: %w", fieldPath, err))
+		}
+	}
+}
+
+// BindField 将配置中 key 的值绑定到单个字段，配置中不存在时使用 `default:"..."` tag 的值，
+// 切片优先使用子配置 `key:0`、`key:1`，否则使用逗号分隔的值
+func BindField(configuration IConfiguration, key string, fv reflect.Value, tag reflect.StructTag) error {
+	if fv.Kind() == reflect.Slice {
+		section := configuration.GetSection(key)
+		if children := section.Children(); len(children) > 0 {
+			sortKeys(children)
+			slice := reflect.MakeSlice(fv.Type(), 0, len(children))
+			for _, child := range children {
+				value, _ := section.Get(child)
+				item := reflect.New(fv.Type().Elem()).Elem()
+				if err := BindValue(item, value); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, item)
+			}
+			fv.Set(slice)
+			return nil
+		}
+	}
+
+	value, ok := configuration.Get(key)
+	if !ok {
+		value, ok = tag.Lookup("default")
+	}
+	if !ok {
+		return nil
+	}
+	return BindValue(fv, value)
+}
+
+// 按数字顺序排序切片下标，非数字的键排在后面
+func sortKeys(keys []string) {
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA != nil || errB != nil {
+			return errA == nil
+		}
+		return a < b
+	})
+}
+
+// BindValue 将字符串转换为 v 的类型并赋值，
+// 支持字符串、布尔、整数、浮点数、time.Duration 以及由它们组成的逗号分隔的切片
+func BindValue(v reflect.Value, value string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		if value != "" {
+			items = strings.Split(value, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := BindValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Ptr:
+		item := reflect.New(v.Type().Elem())
+		if err := BindValue(item.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(item)
+	default:
+		return fmt.Errorf("unsupported type [ %v ]", v.Type())
+	}
+	return nil
+}
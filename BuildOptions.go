@@ -0,0 +1,48 @@
+package goioc
+
+import (
+	"log/slog"
+	"time"
+)
+
+// FailurePolicy 对象实例化失败后的处理策略，
+// 实例化失败包括工厂函数 panic、字段注入失败和初始化接口返回错误
+type FailurePolicy int
+
+const (
+	// RetryOnFailure 不缓存失败结果，下一次获取时重新实例化
+	RetryOnFailure FailurePolicy = iota
+	// CacheFailure 缓存第一次实例化的错误，之后每次获取都返回该错误
+	CacheFailure
+)
+
+// BuildOptions 构建 IServiceProvider 的选项
+type BuildOptions struct {
+	// Scope、Singleton 对象实例化失败后的处理策略，
+	// Singleton 对象使用第一次 Build 时的策略
+	FailurePolicy FailurePolicy
+
+	// 构建时立即按依赖顺序实例化所有 Singleton 对象，见 IServiceProvider.Warmup
+	EagerSingletons bool
+	// Warmup 时同时实例化当前 Provider 的 Scope 对象
+	WarmupScoped bool
+	// Warmup 时最多同时实例化的对象数量，不大于 1 时按确定的顺序依次实例化
+	WarmupParallelism int
+
+	// 记录容器活动的日志，为 nil 时不记录
+	Logger *slog.Logger
+	// 实例化耗时超过此值时记录警告，耗时包括依赖的实例化，为 0 时不记录
+	SlowFactoryThreshold time.Duration
+
+	// 实例化对象期间通过 pprof.Do 设置 services.ServiceLabel 标签，
+	// CPU profile 可以按正在实例化的类型区分耗时
+	ProfilerLabels bool
+
+	// 将容器指标发布到 expvar 的名称，为空时不发布，
+	// 同一个名称多次构建时发布最后一次构建的 Provider 的指标，
+	// 名称已经被其它包使用时 Build 返回错误
+	MetricsName string
+
+	// 容器事件的观察者，作用域使用构建时的 Hooks
+	Hooks Hooks
+}
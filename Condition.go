@@ -0,0 +1,67 @@
+package goioc
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 预定义的环境，也可以使用自定义的环境名称
+const (
+	Development = "Development"
+	Staging     = "Staging"
+	Production  = "Production"
+)
+
+// IConditionContext 评估注册条件时的上下文
+type IConditionContext interface {
+	// Profile 当前环境
+	Profile() string
+	// Configuration 容器中注册的配置，未注册时返回 nil
+	Configuration() IConfiguration
+	// Has 容器中是否已经注册了 t，包括已经满足条件的条件注册
+	Has(t reflect.Type) bool
+}
+
+// Condition 注册条件，在 Build 时按注册顺序评估
+type Condition func(ctx IConditionContext) bool
+
+// OnProfile 当前环境是 profiles 之一时满足条件，不区分大小写
+func OnProfile(profiles ...string) Condition {
+	return func(ctx IConditionContext) bool {
+		for _, profile := range profiles {
+			if strings.EqualFold(ctx.Profile(), profile) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// OnConfig 配置中 key 的值等于 value 时满足条件，不区分大小写，
+// value 为空字符串时只要求配置中存在 key
+func OnConfig(key string, value string) Condition {
+	return func(ctx IConditionContext) bool {
+		configuration := ctx.Configuration()
+		if configuration == nil {
+			return false
+		}
+		v, ok := configuration.Get(key)
+		return ok && (value == "" || strings.EqualFold(v, value))
+	}
+}
+
+// OnPresent 容器中已经注册了 T 时满足条件
+func OnPresent[T any]() Condition {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return func(ctx IConditionContext) bool {
+		return ctx.Has(t)
+	}
+}
+
+// OnMissing 容器中没有注册 T 时满足条件
+func OnMissing[T any]() Condition {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return func(ctx IConditionContext) bool {
+		return !ctx.Has(t)
+	}
+}
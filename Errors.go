@@ -0,0 +1,30 @@
+package goioc
+
+import "strings"
+
+// AggregateError 多个错误的集合，
+// 用于需要一次性报告所有错误的场景
+type AggregateError struct {
+	Errors []error
+}
+
+func (e *AggregateError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap 返回所有错误，支持 errors.Is 和 errors.As
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// 展开 AggregateError，其它错误返回只包含自身的切片
+func unwrapAggregate(err error) []error {
+	if aggregate, ok := err.(*AggregateError); ok {
+		return aggregate.Errors
+	}
+	return []error{err}
+}
@@ -0,0 +1,35 @@
+package goioc
+
+import (
+	"reflect"
+	"time"
+)
+
+// ResolveEvent 获取对象时的事件信息
+type ResolveEvent struct {
+	// 正在获取的类型
+	Type reflect.Type
+	// 对象的生命周期，类型没有注册时为零值
+	Lifetime ServiceLifetime
+	// 解析深度，直接从容器获取时为 0，注入到字段中的对象在其所属对象的深度上加 1
+	Depth int
+	// 被注入的对象类型，直接从容器获取时为 nil
+	Parent reflect.Type
+}
+
+// Hooks 容器事件的观察者，用于日志、指标和追踪，
+// 所有回调都可以为 nil，回调中不应当阻塞
+type Hooks struct {
+	// OnResolving 开始获取对象，包括从容器直接获取以及注入字段时的递归获取
+	OnResolving func(event ResolveEvent)
+	// OnCreated 创建了新的实例，duration 包括字段注入和初始化接口的耗时
+	OnCreated func(event ResolveEvent, instance interface{}, duration time.Duration)
+	// OnResolveFailed 获取对象失败，错误会在依赖链上的每一层触发
+	OnResolveFailed func(event ResolveEvent, err error)
+	// OnScopeCreated 通过 CreateScope 创建了新的作用域
+	OnScopeCreated func(scope IServiceProvider)
+	// OnScopeDisposed 通过 CreateScope 创建的作用域被释放
+	OnScopeDisposed func(scope IServiceProvider)
+	// OnDisposed 释放了实现 IDispose 接口的实例
+	OnDisposed func(baseType reflect.Type, instance interface{})
+}
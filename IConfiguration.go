@@ -0,0 +1,28 @@
+package goioc
+
+// IConfiguration 配置接口，
+// 使用 `:` 分隔的层级键读取配置，如 `Db:Host`，键不区分大小写
+type IConfiguration interface {
+	// Get 获取配置值
+	Get(key string) (string, bool)
+	// GetSection 获取子配置，子配置中的键相对于 key
+	GetSection(key string) IConfiguration
+	// Children 获取当前配置下一级的所有键
+	Children() []string
+}
+
+// IReloadableConfiguration 可以重新加载的配置，
+// IOptionsMonitor 通过它获取配置变化
+type IReloadableConfiguration interface {
+	IConfiguration
+	// OnReload 注册配置重新加载后的回调，
+	// 回调返回的错误由重新加载配置的一方报告，如 config.Configuration.Watch 的 onError
+	OnReload(callback func() error)
+}
+
+// AddConfiguration 将配置注册到容器中，生命周期为 Singleton
+func AddConfiguration(con IServiceCollection, configuration IConfiguration) {
+	AddServiceHandlerOf[IConfiguration, IConfiguration](con, Singleton, func(provider IServiceProvider) interface{} {
+		return configuration
+	})
+}
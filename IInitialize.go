@@ -0,0 +1,17 @@
+package goioc
+
+import "context"
+
+// IInitialize 初始化接口，
+// 对象实例化并完成字段注入后，由 IServiceProvider 调用一次
+type IInitialize interface {
+	// Init 初始化对象，返回错误时对象不会被缓存
+	Init() error
+}
+
+// IInitializeContext 带上下文的初始化接口，
+// 上下文来自 IServiceProvider.GetServiceContext
+type IInitializeContext interface {
+	// InitContext 初始化对象，返回错误时对象不会被缓存
+	InitContext(ctx context.Context) error
+}
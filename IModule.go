@@ -0,0 +1,42 @@
+package goioc
+
+import (
+	"context"
+	"reflect"
+)
+
+// IModule 模块，
+// 将一组相关的服务注册组织在一起，通过 IServiceCollection.AddModule 添加
+type IModule interface {
+	// Name 模块名称，相同名称的模块只会被添加一次
+	Name() string
+	// ConfigureServices 注册模块的服务，通过 sc 注册的服务会记录所属模块
+	ConfigureServices(sc IServiceCollection)
+	// DependsOn 依赖的模块，会在当前模块之前添加
+	DependsOn() []IModule
+}
+
+// IExportingModule 显式声明导出服务的模块，
+// 模块中注册的服务只有 Exports 中的类型可以在模块外部获取和注入，
+// 其余服务是模块私有的，只能注入到同一个模块的服务中
+type IExportingModule interface {
+	IModule
+	// Exports 导出的服务类型
+	Exports() []reflect.Type
+}
+
+// ownerKey AsOwner 在 context 中使用的键
+type ownerKey struct{}
+
+// AsOwner 返回以服务所属模块的身份获取对象的 ctx，
+// 通过 GetServiceContext 获取对象时不检查模块可见性，模块私有的服务同样可以获取。
+// 供 Host 等框架代码获取已注册的服务使用，字段注入仍然检查可见性
+func AsOwner(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ownerKey{}, true)
+}
+
+// IsOwner ctx 是否由 AsOwner 创建
+func IsOwner(ctx context.Context) bool {
+	owner, _ := ctx.Value(ownerKey{}).(bool)
+	return owner
+}
@@ -0,0 +1,139 @@
+package goioc
+
+import (
+	"fmt"
+	"sync"
+)
+
+// IOptions 选项接口，
+// 通过 Configure 从配置中绑定，服务通过 `ioc:"true"` 注入
+type IOptions[T any] interface {
+	// Value 获取选项的值
+	Value() T
+}
+
+// IOptionsMonitor 可以重新加载的选项，
+// 配置实现了 IReloadableConfiguration 时，配置重新加载后选项会重新绑定
+type IOptionsMonitor[T any] interface {
+	// CurrentValue 获取选项当前的值
+	CurrentValue() T
+	// OnChange 注册选项变化后的回调
+	OnChange(listener func(T))
+}
+
+// IOptionsSnapshot 作用域内不变的选项，
+// 值为作用域中第一次获取时 IOptionsMonitor 的当前值
+type IOptionsSnapshot[T any] interface {
+	// Value 获取选项的值
+	Value() T
+}
+
+// options 即 IOptions 的实现
+type options[T any] struct {
+	value T
+	// 绑定或校验的错误
+	err error
+}
+
+func (o *options[T]) Value() T {
+	return o.value
+}
+
+// ValidateOnStart 构建时实例化 IOptions，返回绑定或校验的错误，使构建失败
+func (o *options[T]) ValidateOnStart() error {
+	return o.err
+}
+
+// snapshot 即 IOptionsSnapshot 的实现
+type snapshot[T any] struct {
+	value T
+}
+
+func (o *snapshot[T]) Value() T {
+	return o.value
+}
+
+// optionsMonitor 即 IOptionsMonitor 的实现
+type optionsMonitor[T any] struct {
+	lock      sync.RWMutex
+	value     T
+	listeners []func(T)
+}
+
+func (m *optionsMonitor[T]) CurrentValue() T {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.value
+}
+
+func (m *optionsMonitor[T]) OnChange(listener func(T)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// 更新选项的值并通知所有回调
+func (m *optionsMonitor[T]) set(value T) {
+	m.lock.Lock()
+	m.value = value
+	listeners := append([]func(T){}, m.listeners...)
+	m.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener(value)
+	}
+}
+
+// Configure 注册 IOptions[T]、IOptionsMonitor[T] 和 IOptionsSnapshot[T]，
+// 实例化时从容器中的 IConfiguration 读取 section 并绑定到 T，
+// 配置中不存在的字段使用 `default:"..."` tag 的值，绑定后使用 Validate 校验。
+// IOptions、IOptionsMonitor 的生命周期为 Singleton，IOptionsSnapshot 的生命周期为 Scope；
+// IOptions 会在构建时实例化，配置错误时构建失败，错误中包含每个字段的 *AggregateError。
+func Configure[T any](con IServiceCollection, section string) {
+	AddServiceHandlerOf[IOptions[T], options[T]](con, Singleton, func(provider IServiceProvider) interface{} {
+		configuration := GetI[IConfiguration](provider)
+		value, err := bindOptions[T](configuration, section)
+		return &options[T]{value: value, err: err}
+	})
+
+	AddServiceHandlerOf[IOptionsMonitor[T], optionsMonitor[T]](con, Singleton, func(provider IServiceProvider) interface{} {
+		configuration := GetI[IConfiguration](provider)
+		// 绑定或校验的错误由 IOptions 在构建时报告
+		value, _ := bindOptions[T](configuration, section)
+		monitor := &optionsMonitor[T]{value: value}
+
+		// 重新绑定或校验失败时保留原来的值，并返回错误
+		if reloadable, ok := configuration.(IReloadableConfiguration); ok {
+			reloadable.OnReload(func() error {
+				value, err := bindOptions[T](configuration, section)
+				if err != nil {
+					return fmt.Errorf("reload options [ %s ]: %w", section, err)
+				}
+				monitor.set(value)
+				return nil
+			})
+		}
+		return monitor
+	})
+
+	AddServiceHandlerOf[IOptionsSnapshot[T], snapshot[T]](con, Scope, func(provider IServiceProvider) interface{} {
+		monitor := GetI[IOptionsMonitor[T]](provider)
+		return &snapshot[T]{value: monitor.CurrentValue()}
+	})
+}
+
+// 从配置中绑定选项并校验，绑定和校验的错误会一起返回
+func bindOptions[T any](configuration IConfiguration, section string) (T, error) {
+	var value T
+	var errs []error
+	if err := Bind(configuration.GetSection(section), &value); err != nil {
+		errs = append(errs, unwrapAggregate(err)...)
+	}
+	if err := Validate(&value); err != nil {
+		errs = append(errs, unwrapAggregate(err)...)
+	}
+	if len(errs) > 0 {
+		return value, &AggregateError{Errors: errs}
+	}
+	return value, nil
+}
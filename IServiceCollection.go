@@ -14,8 +14,25 @@ type IServiceCollection interface {
 	// AddServiceHandlerOf 注册一个服务，serviceType 必须继承了 baseType，由开发者决定如何返回实例
 	AddServiceHandlerOf(lifetime ServiceLifetime, baseType reflect.Type, serviceType reflect.Type, f func(provider IServiceProvider) interface{})
 
+	// When 返回一个按条件注册的容器，通过它注册的服务在 Build 时满足条件才会生效，
+	// 并覆盖同类型的普通注册
+	When(condition Condition) IServiceCollection
+	// SetProfile 设置当前环境，如 Development、Production
+	SetProfile(profile string)
+	// Profile 获取当前环境
+	Profile() string
+
+	// AddModule 添加模块，先添加其依赖的模块，相同名称的模块只会添加一次，模块循环依赖时 panic
+	AddModule(module IModule)
+
+	// Descriptors 按注册顺序返回所有生效的注入信息
+	Descriptors() []ServiceDescriptor
+	// Describe 以 JSON 描述所有生效的注册，输出内容稳定，可以用于比较差异
+	Describe() ([]byte, error)
 	// CopyTo 复制当前容器的所有注入信息，生成新的容器
 	CopyTo() IServiceCollection
-	// 	Build() 构建依赖注入服务提供器 IServiceProvider
+	// 	Build() 构建依赖注入服务提供器 IServiceProvider，构建失败时 panic
 	Build() IServiceProvider
+	// BuildWithOptions 使用指定选项构建依赖注入服务提供器 IServiceProvider
+	BuildWithOptions(options BuildOptions) (IServiceProvider, error)
 }
@@ -1,12 +1,24 @@
 package goioc
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
 
 // IServiceProvider 依赖注入提供器，
 // 将类型实例化为对象。
 type IServiceProvider interface {
 	// GetService 获取你需要的服务实例
 	GetService(baseType reflect.Type) (*interface{}, error)
+	// GetServiceContext 获取你需要的服务实例，ctx 会传递给 IInitializeContext
+	GetServiceContext(ctx context.Context, baseType reflect.Type) (*interface{}, error)
+	// Warmup 按依赖顺序提前实例化所有 Singleton 对象，
+	// 返回所有实例化失败的错误
+	Warmup(ctx context.Context) error
+	// CreateScope 创建一个新的作用域，Singleton 对象共享，Scope 对象重新实例化
+	CreateScope() IServiceProvider
+	// Describe 以 JSON 描述所有注册，以及实例是否已经创建
+	Describe() ([]byte, error)
 	// Dispose 释放当前容器的 Scope 对象
 	Dispose()
 }
@@ -0,0 +1,15 @@
+package goioc
+
+// IValidatable 可以自我校验的对象，
+// 选项绑定后会调用 Validate，返回的错误与 validate tag 的错误一起报告
+type IValidatable interface {
+	// Validate 校验对象
+	Validate() error
+}
+
+// IStartupValidator 需要在构建时校验的服务，
+// BuildWithOptions 会实例化所有实现了此接口的服务并执行校验，如 IOptions
+type IStartupValidator interface {
+	// ValidateOnStart 校验服务
+	ValidateOnStart() error
+}
@@ -24,4 +24,20 @@ type ServiceDescriptor struct {
 
 	// 如何实例化对象，要求返回的必须是对象的指针给接口
 	InitHandler func(provider IServiceProvider) interface{}
+
+	// InitHandler 是否由容器生成，通过反射实例化 ServiceType，
+	// 为 false 时 InitHandler 是开发者注册的工厂函数
+	Reflection bool
+
+	// 注册条件，为 nil 时总是生效
+	Condition Condition
+
+	// 注册此服务的模块名称，不是通过模块注册时为空
+	Module string
+
+	// 注册服务的位置，格式为 "目录/文件:行号"，没有记录时为空
+	Source string
+
+	// 是否为模块私有的服务，私有服务只能注入到同一个模块的服务中
+	Private bool
 }
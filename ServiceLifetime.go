@@ -1,5 +1,7 @@
 package goioc
 
+import "fmt"
+
 type ServiceLifetime int
 
 const (
@@ -7,3 +9,15 @@ const (
 	Scope
 	Singleton
 )
+
+func (l ServiceLifetime) String() string {
+	switch l {
+	case Transient:
+		return "Transient"
+	case Scope:
+		return "Scope"
+	case Singleton:
+		return "Singleton"
+	}
+	return fmt.Sprintf("ServiceLifetime(%d)", int(l))
+}
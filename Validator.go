@@ -0,0 +1,112 @@
+package goioc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate 根据 `validate:"required,min=1,max=10"` tag 校验结构体，target 必须是结构体指针。
+// required 表示字段不能为零值；
+// min、max 对于数字限制取值范围，对于字符串、切片和 map 限制长度，对于 time.Duration 使用 `1s` 形式的值；
+// 嵌套的结构体会被递归校验，实现了 IValidatable 的结构体会调用 Validate；
+// 所有字段的错误会通过 *AggregateError 一起返回。
+func Validate(target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("[ %T ] is not a struct pointer", target)
+	}
+	var errs []error
+	validateStruct(v.Elem(), v.Elem().Type().Name(), &errs)
+	if len(errs) > 0 {
+		return &AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, path string, errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := path + "." + field.Name
+		fv := v.Field(i)
+
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			for _, rule := range strings.Split(tag, ",") {
+				if err := validateRule(fv, strings.TrimSpace(rule)); err != nil {
+					*errs = append(*errs, fmt.Errorf("validate [ %s ]: %w", fieldPath, err))
+				}
+			}
+		}
+
+		if fv.Kind() == reflect.Ptr && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			validateStruct(fv, fieldPath, errs)
+		}
+	}
+
+	if v.CanAddr() {
+		if validatable, ok := v.Addr().Interface().(IValidatable); ok {
+			if err := validatable.Validate(); err != nil {
+				*errs = append(*errs, fmt.Errorf("validate [ %s ]: %w", path, err))
+			}
+		}
+	}
+}
+
+// 校验单个规则
+func validateRule(v reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "":
+		return nil
+	case "required":
+		if v.IsZero() {
+			return fmt.Errorf("is required")
+		}
+		return nil
+	case "min", "max":
+		value, limit, err := compareValues(v, arg)
+		if err != nil {
+			return fmt.Errorf("rule [ %s ]: %w", rule, err)
+		}
+		if name == "min" && value < limit {
+			return fmt.Errorf("must be at least %s", arg)
+		}
+		if name == "max" && value > limit {
+			return fmt.Errorf("must be at most %s", arg)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown rule [ %s ]", rule)
+}
+
+// 获取用于 min、max 比较的值
+func compareValues(v reflect.Value, arg string) (float64, float64, error) {
+	if v.Type() == durationType {
+		limit, err := time.ParseDuration(arg)
+		return float64(v.Int()), float64(limit), err
+	}
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), limit, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), limit, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), limit, nil
+	case reflect.String, reflect.Slice, reflect.Map:
+		return float64(v.Len()), limit, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported type [ %v ]", v.Type())
+}
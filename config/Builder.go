@@ -0,0 +1,51 @@
+package config
+
+import "flag"
+
+// Builder 配置构建器，
+// 按添加顺序加载配置源，后添加的配置源覆盖先添加的
+type Builder struct {
+	sources []ISource
+}
+
+// NewBuilder 创建配置构建器
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddSource 添加配置源
+func (b *Builder) AddSource(source ISource) *Builder {
+	b.sources = append(b.sources, source)
+	return b
+}
+
+// AddJsonFile 添加 JSON 文件配置源，optional 为 true 时文件不存在不会报错
+func (b *Builder) AddJsonFile(path string, optional bool) *Builder {
+	return b.AddSource(&JsonSource{Path: path, Optional: optional})
+}
+
+// AddEnvironmentVariables 添加环境变量配置源，只读取以 prefix 开头的环境变量
+func (b *Builder) AddEnvironmentVariables(prefix string) *Builder {
+	return b.AddSource(&EnvironmentSource{Prefix: prefix})
+}
+
+// AddFlags 添加命令行参数配置源，flagSet 需要先完成 Parse
+func (b *Builder) AddFlags(flagSet *flag.FlagSet) *Builder {
+	return b.AddSource(&FlagSource{FlagSet: flagSet})
+}
+
+// AddInMemory 添加内存配置源
+func (b *Builder) AddInMemory(values map[string]string) *Builder {
+	return b.AddSource(&MemorySource{Values: values})
+}
+
+// Build 加载所有配置源，生成配置
+func (b *Builder) Build() (*Configuration, error) {
+	c := &Configuration{
+		root: &root{sources: append([]ISource{}, b.sources...)},
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
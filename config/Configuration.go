@@ -0,0 +1,149 @@
+package config
+
+import (
+	"context"
+	"github.com/whuanle/goioc"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyDelimiter 层级键的分隔符
+const KeyDelimiter = ":"
+
+// Configuration 即 goioc.IConfiguration 的实现，
+// 由多个配置源合并而成，键不区分大小写
+type Configuration struct {
+	root *root
+	// 子配置的前缀，以 KeyDelimiter 结尾
+	prefix string
+}
+
+// 所有子配置共享的数据
+type root struct {
+	lock sync.RWMutex
+	// 保证同一时间只有一次重新加载，配置源的状态在其保护下访问，回调依次执行
+	reload  sync.Mutex
+	sources []ISource
+	// 小写的完整键
+	data map[string]string
+	// 重新加载后的回调
+	callbacks []func() error
+}
+
+// Reload 重新加载所有配置源，任意配置源加载失败时保留原来的配置；
+// 配置更新后回调返回的错误通过 *goioc.AggregateError 一起返回。
+// 多次重新加载依次执行，回调中不能再调用 Reload
+func (c *Configuration) Reload() error {
+	c.root.reload.Lock()
+	defer c.root.reload.Unlock()
+
+	data := map[string]string{}
+	for _, source := range c.root.sources {
+		values, err := source.Load()
+		if err != nil {
+			return err
+		}
+		for key, value := range values {
+			data[strings.ToLower(key)] = value
+		}
+	}
+
+	c.root.lock.Lock()
+	first := c.root.data == nil
+	c.root.data = data
+	callbacks := append([]func() error{}, c.root.callbacks...)
+	c.root.lock.Unlock()
+
+	if first {
+		return nil
+	}
+	var errs []error
+	for _, callback := range callbacks {
+		if err := callback(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+// OnReload 注册配置重新加载后的回调
+func (c *Configuration) OnReload(callback func() error) {
+	c.root.lock.Lock()
+	defer c.root.lock.Unlock()
+	c.root.callbacks = append(c.root.callbacks, callback)
+}
+
+// Watch 每隔 interval 检查一次实现了 IWatchableSource 的配置源，
+// 发生变化时重新加载配置，直到 ctx 取消；
+// 配置源加载失败或回调（如 IOptionsMonitor 重新绑定、校验）返回错误时调用 onError，onError 可以为 nil
+func (c *Configuration) Watch(ctx context.Context, interval time.Duration, onError func(err error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !c.changed() {
+				continue
+			}
+			if err := c.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+}
+
+// 是否有配置源发生变化
+func (c *Configuration) changed() bool {
+	c.root.reload.Lock()
+	defer c.root.reload.Unlock()
+	for _, source := range c.root.sources {
+		if watchable, ok := source.(IWatchableSource); ok && watchable.Changed() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Configuration) Get(key string) (string, bool) {
+	c.root.lock.RLock()
+	defer c.root.lock.RUnlock()
+	value, ok := c.root.data[strings.ToLower(c.prefix+key)]
+	return value, ok
+}
+
+func (c *Configuration) GetSection(key string) goioc.IConfiguration {
+	return &Configuration{
+		root:   c.root,
+		prefix: c.prefix + key + KeyDelimiter,
+	}
+}
+
+func (c *Configuration) Children() []string {
+	c.root.lock.RLock()
+	defer c.root.lock.RUnlock()
+
+	prefix := strings.ToLower(c.prefix)
+	seen := map[string]bool{}
+	var children []string
+	for key := range c.root.data {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		child, _, _ := strings.Cut(key[len(prefix):], KeyDelimiter)
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
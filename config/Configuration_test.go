@@ -0,0 +1,261 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConfiguration_Layered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	json := `{"Db": {"Host": "json", "Port": 3306, "User": "root"}, "Hosts": ["a", "b"]}`
+	if err := os.WriteFile(path, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_DB__PORT", "5432")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("db.user", "", "")
+	flags.String("db.password", "default", "")
+	if err := flags.Parse([]string{"-db.user=admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewBuilder().
+		AddInMemory(map[string]string{"Db:Host": "memory", "Db:Password": "memory"}).
+		AddJsonFile(path, false).
+		AddJsonFile(filepath.Join(t.TempDir(), "missing.json"), true).
+		AddEnvironmentVariables("APP_").
+		AddFlags(flags).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"Db:Host":     "json",
+		"db:port":     "5432",
+		"Db:User":     "admin",
+		"Db:Password": "memory",
+		"Hosts:1":     "b",
+	}
+	for key, value := range expected {
+		if v, _ := c.Get(key); v != value {
+			t.Errorf("%s = %s, expected %s", key, v, value)
+		}
+	}
+
+	db := c.GetSection("Db")
+	if v, _ := db.Get("Host"); v != "json" {
+		t.Errorf("section Db:Host = %s", v)
+	}
+	if children := db.Children(); len(children) != 4 {
+		t.Errorf("children = %v", children)
+	}
+}
+
+func TestConfiguration_MissingFile(t *testing.T) {
+	_, err := NewBuilder().AddJsonFile(filepath.Join(t.TempDir(), "missing.json"), false).Build()
+	if err == nil {
+		t.Errorf("missing file is not reported")
+	}
+}
+
+type ServerOptions struct {
+	Port  int
+	Hosts []string
+}
+
+func TestConfiguration_Options(t *testing.T) {
+	c, err := NewBuilder().AddInMemory(map[string]string{
+		"Server:Port":    "8080",
+		"Server:Hosts:0": "a",
+		"Server:Hosts:1": "b",
+	}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc := &services.ServiceCollection{}
+	goioc.AddConfiguration(sc, c)
+	goioc.Configure[ServerOptions](sc, "Server")
+	p := sc.Build()
+
+	options := goioc.GetI[goioc.IOptions[ServerOptions]](p).Value()
+	if options.Port != 8080 || len(options.Hosts) != 2 {
+		t.Errorf("options are not bound: %+v", options)
+	}
+}
+
+func TestConfiguration_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	if err := os.WriteFile(path, []byte(`{"Server": {"Port": 80}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBuilder().AddJsonFile(path, false).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc := &services.ServiceCollection{}
+	goioc.AddConfiguration(sc, c)
+	goioc.Configure[ServerOptions](sc, "Server")
+	p := sc.Build()
+
+	monitor := goioc.GetI[goioc.IOptionsMonitor[ServerOptions]](p)
+	snapshot := goioc.GetI[goioc.IOptionsSnapshot[ServerOptions]](p)
+	changed := make(chan ServerOptions, 1)
+	monitor.OnChange(func(options ServerOptions) {
+		changed <- options
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Watch(ctx, time.Millisecond, nil)
+	if err := os.WriteFile(path, []byte(`{"Server": {"Port": 8080}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case options := <-changed:
+		if options.Port != 8080 {
+			t.Errorf("changed options = %+v", options)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnChange is not called")
+	}
+	if monitor.CurrentValue().Port != 8080 {
+		t.Errorf("monitor is not updated")
+	}
+
+	// 已经创建的作用域中值不变，新的作用域获取新的值
+	if snapshot.Value().Port != 80 {
+		t.Errorf("snapshot is changed in scope")
+	}
+	scope := p.CreateScope()
+	defer scope.Dispose()
+	if goioc.GetI[goioc.IOptionsSnapshot[ServerOptions]](scope).Value().Port != 8080 {
+		t.Errorf("snapshot in new scope is not updated")
+	}
+}
+
+func TestConfiguration_WatchError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	if err := os.WriteFile(path, []byte(`{"Server": {"Port": 80}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBuilder().AddJsonFile(path, false).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc := &services.ServiceCollection{}
+	goioc.AddConfiguration(sc, c)
+	goioc.Configure[ServerOptions](sc, "Server")
+	p := sc.Build()
+	monitor := goioc.GetI[goioc.IOptionsMonitor[ServerOptions]](p)
+
+	errs := make(chan error, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Watch(ctx, time.Millisecond, func(err error) { errs <- err })
+	wait := func(message string) error {
+		select {
+		case err := <-errs:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal(message)
+		}
+		return nil
+	}
+
+	// 选项绑定失败时报告错误并保留原来的值
+	if err := os.WriteFile(path, []byte(`{"Server": {"Port": "http"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 文件写入过程中可能读到不完整的内容
+	for {
+		if err := wait("options error is not reported"); strings.Contains(err.Error(), "reload options [ Server ]") {
+			break
+		}
+	}
+	if monitor.CurrentValue().Port != 80 {
+		t.Errorf("monitor should keep the previous value")
+	}
+
+	// 解析失败后会重试，直到文件被修正
+	if err := os.WriteFile(path, []byte(`{"Server": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if err := wait("parse error is not reported"); strings.Contains(err.Error(), "parse") {
+			break
+		}
+	}
+	if err := wait("parse error is not retried"); !strings.Contains(err.Error(), "parse") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"Server": {"Port": 8080}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for monitor.CurrentValue().Port != 8080 {
+		if time.Now().After(deadline) {
+			t.Fatal("configuration is not reloaded after the file is fixed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Watch 与显式的 Reload 同时执行时依次重新加载
+func TestConfiguration_ConcurrentReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	if err := os.WriteFile(path, []byte(`{"Server": {"Port": 80}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewBuilder().AddJsonFile(path, false).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var running, overlapped int32
+	c.OnReload(func() error {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.Watch(ctx, time.Millisecond, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				content := fmt.Sprintf(`{"Server": {"Port": %d}}`, i*100+j)
+				_ = os.WriteFile(path, []byte(content), 0644)
+				_ = c.Reload()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&overlapped) != 0 {
+		t.Errorf("reload callbacks overlapped")
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+// ISource 配置源
+type ISource interface {
+	// Load 加载配置，返回扁平化的键值，层级键使用 `:` 分隔，如 `Db:Host`
+	Load() (map[string]string, error)
+}
+
+// IWatchableSource 可以检测变化的配置源，
+// Configuration.Watch 会定期检查，发生变化时重新加载配置
+type IWatchableSource interface {
+	ISource
+	// Changed 配置源自上一次 Load 之后是否发生变化
+	Changed() bool
+}
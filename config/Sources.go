@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JsonSource JSON 文件配置源，对象和数组会被展开为 `Db:Host`、`Hosts:0` 形式的键
+type JsonSource struct {
+	Path string
+	// 文件不存在时是否忽略
+	Optional bool
+
+	// 上一次加载时文件的状态
+	modTime time.Time
+	size    int64
+}
+
+// Load 加载并解析文件，只有加载成功时才记录文件的状态，
+// 加载失败后 Changed 返回 true，下一次检查时会重试
+func (s *JsonSource) Load() (map[string]string, error) {
+	modTime, size := s.stat()
+	content, err := os.ReadFile(s.Path)
+	if err != nil {
+		if s.Optional && errors.Is(err, fs.ErrNotExist) {
+			s.modTime, s.size = modTime, size
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("parse [ %s ]: %w", s.Path, err)
+	}
+	values := map[string]string{}
+	flatten(values, "", root)
+	s.modTime, s.size = modTime, size
+	return values, nil
+}
+
+// Changed 通过修改时间和文件大小判断文件是否发生变化
+func (s *JsonSource) Changed() bool {
+	modTime, size := s.stat()
+	return !modTime.Equal(s.modTime) || size != s.size
+}
+
+// 获取文件的修改时间和大小，文件不存在时返回零值
+func (s *JsonSource) stat() (time.Time, int64) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// 展开 JSON 对象
+func flatten(values map[string]string, prefix string, value interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + KeyDelimiter + key
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			flatten(values, join(key), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(values, join(strconv.Itoa(i)), item)
+		}
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(v)
+	}
+}
+
+// EnvironmentSource 环境变量配置源，
+// 只读取以 Prefix 开头的环境变量，去掉前缀后使用 `__` 表示层级，如 `APP_DB__HOST` 对应 `Db:Host`
+type EnvironmentSource struct {
+	Prefix string
+}
+
+func (s *EnvironmentSource) Load() (map[string]string, error) {
+	values := map[string]string{}
+	for _, env := range os.Environ() {
+		key, value, ok := strings.Cut(env, "=")
+		if !ok || !strings.HasPrefix(key, s.Prefix) {
+			continue
+		}
+		key = strings.ReplaceAll(strings.TrimPrefix(key, s.Prefix), "__", KeyDelimiter)
+		values[key] = value
+	}
+	return values, nil
+}
+
+// FlagSource 命令行参数配置源，使用 `.` 表示层级，如 `-db.host` 对应 `Db:Host`，
+// 只读取命令行中显式设置的参数，未设置的参数不会覆盖其它配置源
+type FlagSource struct {
+	FlagSet *flag.FlagSet
+}
+
+func (s *FlagSource) Load() (map[string]string, error) {
+	values := map[string]string{}
+	s.FlagSet.Visit(func(f *flag.Flag) {
+		values[strings.ReplaceAll(f.Name, ".", KeyDelimiter)] = f.Value.String()
+	})
+	return values, nil
+}
+
+// MemorySource 内存配置源
+type MemorySource struct {
+	Values map[string]string
+}
+
+func (s *MemorySource) Load() (map[string]string, error) {
+	values := make(map[string]string, len(s.Values))
+	for key, value := range s.Values {
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,125 @@
+// Package debug 提供查看容器运行状态的 HTTP 接口，
+// 类似 net/http/pprof，可以挂载到内部管理端口：
+//
+//	mux.Handle("/debug/goioc/", http.StripPrefix("/debug/goioc", debug.Handler(provider)))
+package debug
+
+import (
+	"encoding/json"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// inspectable 可以查看运行状态的 IServiceProvider，*services.ServiceProvider 实现了此接口
+type inspectable interface {
+	goioc.IServiceProvider
+	Diagnostics() services.Diagnostics
+	Graph(format services.GraphFormat) (string, error)
+}
+
+// document JSON 输出的内容
+type document struct {
+	Registrations json.RawMessage      `json:"registrations"`
+	State         services.Diagnostics `json:"state"`
+	Graph         string               `json:"graph"`
+}
+
+// Handler 返回查看 provider 运行状态的 http.Handler：
+//
+//	/        HTML 页面，?format=json 时输出 JSON
+//	/graph   依赖图，?format=mermaid 时输出 Mermaid，默认为 DOT
+func Handler(provider goioc.IServiceProvider) http.Handler {
+	return &handler{provider: provider}
+}
+
+type handler struct {
+	provider goioc.IServiceProvider
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.provider.(inspectable)
+	if !ok {
+		http.Error(w, "provider does not support diagnostics", http.StatusNotImplemented)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if strings.HasSuffix(r.URL.Path, "/graph") {
+		if format == "" {
+			format = string(services.GraphDOT)
+		}
+		graph, err := provider.Graph(services.GraphFormat(format))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(graph))
+		return
+	}
+
+	doc, err := newDocument(provider)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(doc)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func newDocument(provider inspectable) (*document, error) {
+	registrations, err := provider.Describe()
+	if err != nil {
+		return nil, err
+	}
+	graph, err := provider.Graph(services.GraphDOT)
+	if err != nil {
+		return nil, err
+	}
+	return &document{
+		Registrations: registrations,
+		State:         provider.Diagnostics(),
+		Graph:         graph,
+	}, nil
+}
+
+var page = template.Must(template.New("goioc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goioc</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<p><a href="?format=json">json</a> | graph: <a href="graph?format=dot">dot</a> <a href="graph?format=mermaid">mermaid</a></p>
+<h2>Services</h2>
+<table>
+<tr><th>Base type</th><th>Service type</th><th>Lifetime</th><th>Created</th><th>Count</th><th>Last created</th><th>Last duration</th></tr>
+{{range .State.Services}}<tr><td>{{.BaseType}}</td><td>{{.ServiceType}}</td><td>{{.Lifetime}}</td><td>{{.Created}}</td><td>{{.Count}}</td><td>{{if .Count}}{{.LastCreated.Format "2006-01-02 15:04:05.000"}}{{end}}</td><td>{{if .Count}}{{.LastDuration}}{{end}}</td></tr>
+{{end}}</table>
+<h2>Active scopes ({{len .State.Scopes}})</h2>
+<table>
+<tr><th>Id</th><th>Created</th></tr>
+{{range .State.Scopes}}<tr><td>{{.Id}}</td><td>{{.Created.Format "2006-01-02 15:04:05.000"}}</td></tr>
+{{end}}</table>
+<h2>Dependency graph</h2>
+<pre>{{.Graph}}</pre>
+</body>
+</html>
+`))
@@ -0,0 +1,89 @@
+package debug
+
+import (
+	"encoding/json"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Clock struct{}
+
+type Job struct {
+	Clock *Clock `ioc:"true"`
+}
+
+func TestHandler(t *testing.T) {
+	sc := &services.ServiceCollection{}
+	goioc.AddService[Clock](sc, goioc.Singleton)
+	goioc.AddService[Job](sc, goioc.Scope)
+	p := sc.Build()
+	scope := p.CreateScope()
+	goioc.GetS[Job](scope)
+	handler := Handler(p)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/?format=json", nil))
+	var doc document
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.State.Scopes) != 1 {
+		t.Errorf("active scopes = %d, expected 1", len(doc.State.Scopes))
+	}
+	for _, service := range doc.State.Services {
+		if service.BaseType == "debug.Clock" && (!service.Created || service.Count != 1) {
+			t.Errorf("singleton state is not recorded: %+v", service)
+		}
+	}
+	if !strings.Contains(string(doc.Registrations), `"baseType": "debug.Job"`) {
+		t.Errorf("registrations are not included: %s", doc.Registrations)
+	}
+
+	scope.Dispose()
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	if body := recorder.Body.String(); !strings.Contains(body, "Active scopes (0)") || !strings.Contains(body, "debug.Job") {
+		t.Errorf("unexpected html:\n%s", body)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/graph?format=mermaid", nil))
+	if !strings.HasPrefix(recorder.Body.String(), "graph LR") {
+		t.Errorf("unexpected graph:\n%s", recorder.Body.String())
+	}
+}
+
+func TestHandlerDuringConstruction(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	sc := &services.ServiceCollection{}
+	goioc.AddServiceHandler[Clock](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		close(started)
+		<-release
+		return &Clock{}
+	})
+	p := sc.Build()
+	defer close(release)
+
+	go goioc.GetS[Clock](p)
+	<-started
+
+	done := make(chan string)
+	go func() {
+		recorder := httptest.NewRecorder()
+		Handler(p).ServeHTTP(recorder, httptest.NewRequest("GET", "/?format=json", nil))
+		done <- recorder.Body.String()
+	}()
+	select {
+	case body := <-done:
+		if !strings.Contains(body, `"created": false`) {
+			t.Errorf("singleton under construction should not be created:\n%s", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("debug page is blocked by the singleton under construction")
+	}
+}
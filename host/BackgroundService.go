@@ -0,0 +1,168 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"log/slog"
+	"reflect"
+	"time"
+)
+
+// IBackgroundService 长时间运行的后台任务
+type IBackgroundService interface {
+	// Execute 在独立的 goroutine 中执行，ctx 在 Host 停止时取消
+	Execute(ctx context.Context) error
+}
+
+// CrashPolicy 后台任务异常退出后的处理方式，
+// Execute 返回错误或 panic 且 ctx 未取消时视为异常退出
+type CrashPolicy int
+
+const (
+	// StopHost 停止 Host，错误作为 Host.Run 的返回值
+	StopHost CrashPolicy = iota
+	// Restart 等待一段时间后重新执行，等待时间每次翻倍
+	Restart
+)
+
+// BackgroundOptions 后台任务选项
+type BackgroundOptions struct {
+	// 异常退出后的处理方式
+	CrashPolicy CrashPolicy
+	// 第一次重启前的等待时间，默认 1 秒
+	Backoff time.Duration
+	// 重启前的最长等待时间，默认 1 分钟；
+	// 一次执行持续的时间不少于 MaxBackoff 时，等待时间恢复为 Backoff
+	MaxBackoff time.Duration
+	// 策略为 Restart 时，每次异常退出后、等待重启前调用，
+	// 为 nil 时通过 slog.Default() 记录错误
+	OnError func(err error)
+	// 每次执行时创建新的作用域，执行结束后释放
+	Scoped bool
+}
+
+// AddBackgroundService 注册一个后台任务，
+// T 的生命周期为 Transient，每次执行时从容器中获取，字段通过 ioc tag 注入
+func AddBackgroundService[T any](con goioc.IServiceCollection, options BackgroundOptions) {
+	if options.Backoff <= 0 {
+		options.Backoff = time.Second
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = time.Minute
+	}
+	goioc.AddService[T](con, goioc.Transient)
+	goioc.AddServiceHandler[backgroundService[T]](con, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		return &backgroundService[T]{
+			provider: provider,
+			options:  options,
+		}
+	})
+}
+
+// backgroundService 将 IBackgroundService 包装为 IHostedService
+type backgroundService[T any] struct {
+	lifetime IApplicationLifetime `ioc:"true,unexported"`
+	provider goioc.IServiceProvider
+	options  BackgroundOptions
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (b *backgroundService[T]) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.done = make(chan struct{})
+	go b.run(runCtx)
+	return nil
+}
+
+func (b *backgroundService[T]) Stop(ctx context.Context) error {
+	if b.cancel == nil {
+		return nil
+	}
+	b.cancel()
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 执行后台任务，异常退出时根据 CrashPolicy 停止 Host 或重启
+func (b *backgroundService[T]) run(ctx context.Context) {
+	defer close(b.done)
+	backoff := b.options.Backoff
+	for {
+		start := time.Now()
+		err := b.execute(ctx)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if b.options.CrashPolicy == StopHost {
+			b.lifetime.StopApplication(err)
+			return
+		}
+
+		b.report(err)
+		// 运行了较长时间后才退出，视为新的异常，重新从 Backoff 开始等待
+		if time.Since(start) >= b.options.MaxBackoff {
+			backoff = b.options.Backoff
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > b.options.MaxBackoff {
+			backoff = b.options.MaxBackoff
+		}
+	}
+}
+
+// 报告异常退出的错误
+func (b *backgroundService[T]) report(err error) {
+	if b.options.OnError != nil {
+		b.options.OnError(err)
+		return
+	}
+	slog.Default().Error("background service crashed, restarting",
+		slog.String("service", reflect.TypeOf((*T)(nil)).Elem().String()),
+		slog.Any("error", err))
+}
+
+// 执行一次后台任务，panic 会被转换为错误
+func (b *backgroundService[T]) execute(ctx context.Context) (err error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("background service [ %v ] panicked: %v", t, r)
+		}
+	}()
+
+	provider := b.provider
+	if b.options.Scoped {
+		provider = provider.CreateScope()
+		defer provider.Dispose()
+	}
+
+	// T 与后台任务注册在同一个模块中，以所属模块的身份获取
+	obj, err := provider.GetServiceContext(goioc.AsOwner(ctx), t)
+	if err != nil {
+		return fmt.Errorf("background service [ %v ]: %w", t, err)
+	}
+	service, ok := (*obj).(IBackgroundService)
+	if !ok {
+		return fmt.Errorf("[ %v ] is not an IBackgroundService", t)
+	}
+	if err := service.Execute(ctx); err != nil {
+		return fmt.Errorf("background service [ %v ]: %w", t, err)
+	}
+	return nil
+}
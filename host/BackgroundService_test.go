@@ -0,0 +1,128 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 记录执行次数，每次执行时向 Started 发送信号
+type Counter struct {
+	Count   int32
+	Started chan struct{}
+}
+
+func (my *Counter) start() {
+	atomic.AddInt32(&my.Count, 1)
+	select {
+	case my.Started <- struct{}{}:
+	default:
+	}
+}
+
+func addCounter(sc goioc.IServiceCollection) *Counter {
+	counter := &Counter{Started: make(chan struct{}, 16)}
+	goioc.AddServiceHandler[Counter](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		return counter
+	})
+	return counter
+}
+
+// 正常运行直到 Host 停止
+type Worker struct {
+	Counter *Counter `ioc:"true"`
+}
+
+func (my *Worker) Execute(ctx context.Context) error {
+	my.Counter.start()
+	<-ctx.Done()
+	return nil
+}
+
+// 每次执行都失败
+type CrashWorker struct {
+	Counter *Counter `ioc:"true"`
+}
+
+func (my *CrashWorker) Execute(ctx context.Context) error {
+	my.Counter.start()
+	return errors.New("crash")
+}
+
+// 在后台运行 Host，返回 Run 的结果
+func runHost(ctx context.Context, sc goioc.IServiceCollection) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		result <- New(sc).Run(ctx)
+	}()
+	return result
+}
+
+func TestBackgroundService(t *testing.T) {
+	sc := &services.ServiceCollection{}
+	counter := addCounter(sc)
+	AddBackgroundService[Worker](sc, BackgroundOptions{Scoped: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := runHost(ctx, sc)
+	<-counter.Started
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&counter.Count) != 1 {
+		t.Errorf("worker executed %d times", counter.Count)
+	}
+}
+
+func TestBackgroundService_StopHost(t *testing.T) {
+	sc := &services.ServiceCollection{}
+	addCounter(sc)
+	AddBackgroundService[CrashWorker](sc, BackgroundOptions{})
+
+	// 没有其它停止条件，Host 只会因为后台任务异常退出而停止
+	err := New(sc).Run(context.Background())
+	if err == nil || err.Error() != "background service [ host.CrashWorker ]: crash" {
+		t.Errorf("crash is not reported to host: %v", err)
+	}
+}
+
+func TestBackgroundService_Restart(t *testing.T) {
+	sc := &services.ServiceCollection{}
+	counter := addCounter(sc)
+	errs := make(chan error, 16)
+	AddBackgroundService[CrashWorker](sc, BackgroundOptions{
+		CrashPolicy: Restart,
+		Backoff:     time.Millisecond,
+		MaxBackoff:  2 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	result := runHost(ctx, sc)
+	// 每次异常退出都会报告错误，然后重启
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err.Error() != "background service [ host.CrashWorker ]: crash" {
+			t.Errorf("unexpected error: %v", err)
+		}
+		<-counter.Started
+	}
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&counter.Count) < 3 {
+		t.Errorf("worker is not restarted, executed %d times", counter.Count)
+	}
+}
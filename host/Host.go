@@ -0,0 +1,184 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Host 应用主机，
+// 负责构建 IServiceProvider、启动和停止所有 IHostedService，并在退出时释放 IServiceProvider
+type Host struct {
+	// 构建 IServiceProvider 时使用的选项
+	BuildOptions goioc.BuildOptions
+	// ILifecycle 中单个 OnStart 钩子的默认超时时间，默认 15 秒
+	StartTimeout time.Duration
+	// 停止服务的超时时间，默认 30 秒
+	ShutdownTimeout time.Duration
+	// 触发停止的系统信号，默认 SIGINT 和 SIGTERM
+	Signals []os.Signal
+
+	services goioc.IServiceCollection
+	provider goioc.IServiceProvider
+	// 已启动的服务，按启动顺序排列
+	started []IHostedService
+	// 生命周期钩子
+	lifecycle *lifecycle
+
+	// StopApplication 触发的停止信号及原因
+	stopping chan struct{}
+	stopOnce sync.Once
+	stopErr  error
+}
+
+// New 创建应用主机，并将 IApplicationLifetime、ILifecycle 注册到容器中
+func New(sc goioc.IServiceCollection) *Host {
+	h := &Host{
+		StartTimeout:    15 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		Signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		services:        sc,
+		lifecycle:       &lifecycle{},
+		stopping:        make(chan struct{}),
+	}
+	goioc.AddServiceHandlerOf[IApplicationLifetime, Host](sc, goioc.Transient, func(provider goioc.IServiceProvider) interface{} {
+		return h
+	})
+	goioc.AddServiceHandlerOf[ILifecycle, lifecycle](sc, goioc.Transient, func(provider goioc.IServiceProvider) interface{} {
+		return h.lifecycle
+	})
+	return h
+}
+
+// Provider 获取 Host 构建的 IServiceProvider，Start 之前为 nil
+func (h *Host) Provider() goioc.IServiceProvider {
+	return h.provider
+}
+
+// Run 启动 Host，等待系统信号、ctx 取消或 StopApplication 后停止 Host，
+// 启动过程中收到系统信号时，传递给服务的 ctx 会被取消
+func (h *Host) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, h.Signals...)
+	defer stop()
+	if err := h.Start(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-h.stopping:
+	}
+	stop()
+	// 与 StopApplication 同步，之后读取 stopErr 是安全的
+	h.stopOnce.Do(func() { close(h.stopping) })
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), h.ShutdownTimeout)
+	defer cancel()
+	err := h.Stop(stopCtx)
+	if h.stopErr == nil {
+		return err
+	}
+	if err != nil {
+		return &goioc.AggregateError{Errors: []error{h.stopErr, err}}
+	}
+	return h.stopErr
+}
+
+// StopApplication 请求停止 Host，只有第一次调用生效
+func (h *Host) StopApplication(err error) {
+	h.stopOnce.Do(func() {
+		h.stopErr = err
+		close(h.stopping)
+	})
+}
+
+// Start 构建 IServiceProvider，执行 ILifecycle 的 OnStart 钩子，然后按注册顺序启动所有 IHostedService，
+// 任意钩子或服务启动失败时，已经启动的钩子和服务会被停止
+func (h *Host) Start(ctx context.Context) error {
+	provider, err := h.services.BuildWithOptions(h.BuildOptions)
+	if err != nil {
+		return err
+	}
+	h.provider = provider
+
+	// 先获取所有服务，工厂函数在实例化时追加的钩子才能被执行
+	services, err := h.hostedServices(ctx)
+	if err != nil {
+		return h.abort(err)
+	}
+	if err := h.lifecycle.start(ctx, h.StartTimeout); err != nil {
+		return h.abort(err)
+	}
+
+	for _, service := range services {
+		if err := service.Start(ctx); err != nil {
+			return h.abort(fmt.Errorf("start [ %T ]: %w", service, err))
+		}
+		h.started = append(h.started, service)
+	}
+	return nil
+}
+
+// 启动失败时停止已经启动的钩子和服务
+func (h *Host) abort(err error) error {
+	stopCtx, cancel := context.WithTimeout(context.Background(), h.ShutdownTimeout)
+	defer cancel()
+	if stopErr := h.Stop(stopCtx); stopErr != nil {
+		return &goioc.AggregateError{Errors: []error{err, stopErr}}
+	}
+	return err
+}
+
+// Stop 按启动的相反顺序停止所有 IHostedService，再按相反顺序执行 ILifecycle 的 OnStop 钩子，
+// 最后释放 IServiceProvider
+func (h *Host) Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(h.started) - 1; i >= 0; i-- {
+		service := h.started[i]
+		if err := service.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop [ %T ]: %w", service, err))
+		}
+	}
+	h.started = nil
+
+	if err := h.lifecycle.stop(ctx, h.ShutdownTimeout); err != nil {
+		errs = append(errs, err)
+	}
+
+	if h.provider != nil {
+		h.provider.Dispose()
+	}
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+// 按注册顺序获取所有 IHostedService
+func (h *Host) hostedServices(ctx context.Context) ([]IHostedService, error) {
+	hostedType := reflect.TypeOf((*IHostedService)(nil)).Elem()
+
+	var services []IHostedService
+	for _, descriptor := range h.services.Descriptors() {
+		if !descriptor.BaseType.Implements(hostedType) && !reflect.PtrTo(descriptor.BaseType).Implements(hostedType) {
+			continue
+		}
+		// 模块私有的 IHostedService 同样需要启动
+		obj, err := h.provider.GetServiceContext(goioc.AsOwner(ctx), descriptor.BaseType)
+		if err != nil {
+			return nil, err
+		}
+		service, ok := (*obj).(IHostedService)
+		if !ok {
+			return nil, fmt.Errorf("[ %T ] is not an IHostedService", *obj)
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
@@ -0,0 +1,8 @@
+package host
+
+import "github.com/whuanle/goioc"
+
+// AddHostedService 注册一个 IHostedService，生命周期为 Singleton
+func AddHostedService[T any](con goioc.IServiceCollection) {
+	goioc.AddService[T](con, goioc.Singleton)
+}
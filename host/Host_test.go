@@ -0,0 +1,216 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/config"
+	"github.com/whuanle/goioc/services"
+	"os"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var events []string
+
+type Db struct{}
+
+func (my *Db) Start(ctx context.Context) error {
+	events = append(events, "db start")
+	return nil
+}
+
+func (my *Db) Stop(ctx context.Context) error {
+	events = append(events, "db stop")
+	return nil
+}
+
+type Web struct {
+	Db *Db `ioc:"true"`
+}
+
+func (my *Web) Start(ctx context.Context) error {
+	events = append(events, "web start")
+	return nil
+}
+
+func (my *Web) Stop(ctx context.Context) error {
+	events = append(events, "web stop")
+	return nil
+}
+
+type Broken struct{}
+
+func (my *Broken) Start(ctx context.Context) error {
+	return errors.New("broken")
+}
+
+func (my *Broken) Stop(ctx context.Context) error {
+	events = append(events, "broken stop")
+	return nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHost_Run(t *testing.T) {
+	events = nil
+	sc := &services.ServiceCollection{}
+	AddHostedService[Db](sc)
+	AddHostedService[Web](sc)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := New(sc).Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"db start", "web start", "web stop", "db stop"}
+	if !equal(events, expected) {
+		t.Errorf("events = %v, expected %v", events, expected)
+	}
+}
+
+func TestHost_StartError(t *testing.T) {
+	events = nil
+	sc := &services.ServiceCollection{}
+	AddHostedService[Db](sc)
+	goioc.AddService[Broken](sc, goioc.Singleton)
+
+	if err := New(sc).Start(context.Background()); err == nil {
+		t.Fatal("start error is not returned")
+	}
+
+	// 启动失败的服务不需要停止，已经启动的服务需要停止
+	expected := []string{"db start", "db stop"}
+	if !equal(events, expected) {
+		t.Errorf("events = %v, expected %v", events, expected)
+	}
+}
+
+// 启动时等待 ctx 取消的服务
+type Slow struct{}
+
+var slowStarting = make(chan struct{}, 1)
+
+func (my *Slow) Start(ctx context.Context) error {
+	slowStarting <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (my *Slow) Stop(ctx context.Context) error {
+	events = append(events, "slow stop")
+	return nil
+}
+
+func TestHost_RunSignalDuringStart(t *testing.T) {
+	events = nil
+	sc := &services.ServiceCollection{}
+	AddHostedService[Db](sc)
+	AddHostedService[Slow](sc)
+
+	h := New(sc)
+	h.Signals = []os.Signal{syscall.SIGHUP}
+	go func() {
+		<-slowStarting
+		process, _ := os.FindProcess(os.Getpid())
+		_ = process.Signal(syscall.SIGHUP)
+	}()
+	if err := h.Run(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("start should be cancelled by the signal, got %v", err)
+	}
+
+	// 已经启动的服务需要停止
+	expected := []string{"db start", "db stop"}
+	if !equal(events, expected) {
+		t.Errorf("events = %v, expected %v", events, expected)
+	}
+}
+
+// 模块私有的服务
+type Conn struct{}
+
+type ConnOptions struct {
+	Url string `validate:"required"`
+}
+
+// 模块私有的 IHostedService
+type Pool struct {
+	Conn    *Conn                       `ioc:"true"`
+	Options goioc.IOptions[ConnOptions] `ioc:"true"`
+}
+
+func (my *Pool) Start(ctx context.Context) error { return nil }
+
+func (my *Pool) Stop(ctx context.Context) error { return nil }
+
+// 模块私有的后台任务，开始执行时发送信号
+type Cleaner struct {
+	Conn *Conn `ioc:"true"`
+}
+
+var cleanerStarted = make(chan struct{}, 1)
+
+func (my *Cleaner) Execute(ctx context.Context) error {
+	select {
+	case cleanerStarted <- struct{}{}:
+	default:
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// 不导出任何服务的模块
+type dataModule struct{}
+
+func (m *dataModule) Name() string { return "data" }
+
+func (m *dataModule) DependsOn() []goioc.IModule { return nil }
+
+func (m *dataModule) Exports() []reflect.Type { return []reflect.Type{} }
+
+func (m *dataModule) ConfigureServices(sc goioc.IServiceCollection) {
+	goioc.AddService[Conn](sc, goioc.Singleton)
+	goioc.Configure[ConnOptions](sc, "Conn")
+	AddHostedService[Pool](sc)
+	AddBackgroundService[Cleaner](sc, BackgroundOptions{Scoped: true, CrashPolicy: StopHost})
+}
+
+func TestHost_PrivateModule(t *testing.T) {
+	configuration, err := config.NewBuilder().AddInMemory(map[string]string{"Conn:Url": "db://"}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := &services.ServiceCollection{}
+	goioc.AddConfiguration(sc, configuration)
+	sc.AddModule(&dataModule{})
+
+	h := New(sc)
+	h.BuildOptions = goioc.BuildOptions{EagerSingletons: true}
+	if err := h.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-cleanerStarted:
+	case <-h.stopping:
+		t.Fatalf("background service failed: %v", h.stopErr)
+	}
+	if _, err := h.Provider().GetService(reflect.TypeOf(Conn{})); err == nil {
+		t.Errorf("private service should not be available outside the module")
+	}
+	if err := h.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,8 @@
+package host
+
+// IApplicationLifetime 应用生命周期，
+// 由 Host 注册到容器中，服务可以通过 `ioc:"true"` 注入后主动停止 Host
+type IApplicationLifetime interface {
+	// StopApplication 请求停止 Host，err 不为 nil 时会作为 Run 的返回值
+	StopApplication(err error)
+}
@@ -0,0 +1,12 @@
+package host
+
+import "context"
+
+// IHostedService 由 Host 管理的服务，
+// Host 启动时按注册顺序调用 Start，停止时按相反顺序调用 Stop
+type IHostedService interface {
+	// Start 启动服务，返回错误时 Host 启动失败
+	Start(ctx context.Context) error
+	// Stop 停止服务，ctx 在超过 Host.ShutdownTimeout 后取消
+	Stop(ctx context.Context) error
+}
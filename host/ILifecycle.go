@@ -0,0 +1,24 @@
+package host
+
+import (
+	"context"
+	"time"
+)
+
+// Hook 生命周期钩子
+type Hook struct {
+	// Host 启动时执行，返回错误时 Host 启动失败
+	OnStart func(ctx context.Context) error
+	// Host 停止时执行
+	OnStop func(ctx context.Context) error
+	// 单个钩子的超时时间，为 0 时启动使用 Host.StartTimeout，停止使用 Host.ShutdownTimeout
+	Timeout time.Duration
+}
+
+// ILifecycle 生命周期钩子注册表，
+// 由 Host 注册到容器中，工厂函数可以通过 IServiceProvider 获取并追加钩子，
+// Host 启动时按追加顺序执行 OnStart，停止时按相反顺序执行 OnStop
+type ILifecycle interface {
+	// Append 追加一个钩子
+	Append(hook Hook)
+}
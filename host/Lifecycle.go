@@ -0,0 +1,87 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"sync"
+	"time"
+)
+
+// lifecycle 即 ILifecycle 的实现
+type lifecycle struct {
+	lock  sync.Mutex
+	hooks []Hook
+	// 已经执行过 OnStart 的钩子数量，停止时只处理这些钩子
+	started int
+}
+
+func (l *lifecycle) Append(hook Hook) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// 按追加顺序执行 OnStart，遇到错误立即返回
+func (l *lifecycle) start(ctx context.Context, timeout time.Duration) error {
+	for {
+		l.lock.Lock()
+		if l.started >= len(l.hooks) {
+			l.lock.Unlock()
+			return nil
+		}
+		hook := l.hooks[l.started]
+		l.lock.Unlock()
+
+		if hook.OnStart != nil {
+			if err := runHook(ctx, hook.OnStart, hook.Timeout, timeout); err != nil {
+				return fmt.Errorf("lifecycle hook %d OnStart: %w", l.started, err)
+			}
+		}
+		l.lock.Lock()
+		l.started++
+		l.lock.Unlock()
+	}
+}
+
+// 按相反顺序执行已启动钩子的 OnStop，返回所有错误
+func (l *lifecycle) stop(ctx context.Context, timeout time.Duration) error {
+	l.lock.Lock()
+	hooks := l.hooks[:l.started]
+	l.started = 0
+	l.lock.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if hooks[i].OnStop == nil {
+			continue
+		}
+		if err := runHook(ctx, hooks[i].OnStop, hooks[i].Timeout, timeout); err != nil {
+			errs = append(errs, fmt.Errorf("lifecycle hook %d OnStop: %w", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+// 在超时时间内执行钩子，超时后不再等待钩子返回
+func runHook(ctx context.Context, f func(ctx context.Context) error, timeout time.Duration, defaultTimeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- f(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
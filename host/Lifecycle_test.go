@@ -0,0 +1,90 @@
+package host
+
+import (
+	"context"
+	"errors"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"testing"
+	"time"
+)
+
+// 在工厂函数中追加钩子
+type Listener struct{}
+
+func (my *Listener) Start(ctx context.Context) error {
+	events = append(events, "listener start")
+	return nil
+}
+
+func (my *Listener) Stop(ctx context.Context) error {
+	events = append(events, "listener stop")
+	return nil
+}
+
+func addListener(sc goioc.IServiceCollection, name string, onStart error) {
+	goioc.AddServiceHandler[Listener](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		lc := goioc.GetI[ILifecycle](provider)
+		lc.Append(Hook{
+			OnStart: func(ctx context.Context) error {
+				events = append(events, name+" open")
+				return onStart
+			},
+			OnStop: func(ctx context.Context) error {
+				events = append(events, name+" close")
+				return nil
+			},
+		})
+		return &Listener{}
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	events = nil
+	sc := &services.ServiceCollection{}
+	h := New(sc)
+	addListener(sc, "listener", nil)
+
+	if err := h.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"listener open", "listener start", "listener stop", "listener close"}
+	if !equal(events, expected) {
+		t.Errorf("events = %v, expected %v", events, expected)
+	}
+}
+
+func TestLifecycle_StartError(t *testing.T) {
+	events = nil
+	sc := &services.ServiceCollection{}
+	h := New(sc)
+	addListener(sc, "listener", errors.New("address in use"))
+
+	if err := h.Start(context.Background()); err == nil {
+		t.Fatal("hook error is not returned")
+	}
+
+	// 启动失败的钩子不会执行 OnStop，服务不会被启动
+	expected := []string{"listener open"}
+	if !equal(events, expected) {
+		t.Errorf("events = %v, expected %v", events, expected)
+	}
+}
+
+func TestLifecycle_Timeout(t *testing.T) {
+	l := &lifecycle{}
+	l.Append(Hook{
+		OnStart: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Timeout: time.Millisecond,
+	})
+	if err := l.start(context.Background(), time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("hook timeout is not applied: %v", err)
+	}
+}
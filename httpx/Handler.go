@@ -0,0 +1,188 @@
+package httpx
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+)
+
+var (
+	responseWriterType = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	requestPtrType     = reflect.TypeOf(&http.Request{})
+	errorType          = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// IHandler 由容器实例化的 HTTP 处理器，
+// 处理器需要注册到容器中，每个请求从请求的作用域中获取，字段会被注入
+type IHandler interface {
+	http.Handler
+}
+
+// Handle 在 mux 中注册 H 类型的处理器，H 可以是接口或结构体指针，
+// 每个请求从 Middleware 创建的作用域中获取 H 的实例并处理请求
+func Handle[H IHandler](mux *http.ServeMux, pattern string) {
+	mux.Handle(pattern, Handler[H]())
+}
+
+// Handler 返回从请求的作用域中获取 H 的实例并处理请求的 http.Handler
+func Handler[H IHandler]() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, err := Get[H](r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// HandleFunc 在 mux 中注册函数处理器，见 HandlerFunc
+func HandleFunc(mux *http.ServeMux, pattern string, f interface{}) {
+	mux.Handle(pattern, HandlerFunc(f))
+}
+
+// HandlerFunc 将函数转换为 http.Handler，函数的前两个参数必须是 http.ResponseWriter 和 *http.Request，
+// 其余参数从请求的作用域中获取，可以是接口、结构体或结构体指针，
+// 传入的 http.ResponseWriter 保留原始的 http.Flusher、http.Hijacker、http.Pusher 接口，
+// 函数可以返回 error，返回的错误会以 500 响应，函数已经写入了响应或接管了连接时不再写入：
+//
+//	func(w http.ResponseWriter, r *http.Request, users IUserService) error
+//
+// f 不是符合要求的函数时 panic
+func HandlerFunc(f interface{}) http.Handler {
+	fv := reflect.ValueOf(f)
+	ft := fv.Type()
+	if err := checkHandlerFunc(ft); err != nil {
+		panic(err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := Scope(r)
+		if scope == nil {
+			http.Error(w, "request is not in a scope created by httpx.Middleware", http.StatusInternalServerError)
+			return
+		}
+
+		// 记录函数是否已经写入了响应
+		wrapped, rw := wrapResponseWriter(w)
+		args := make([]reflect.Value, ft.NumIn())
+		args[0] = reflect.ValueOf(wrapped)
+		args[1] = reflect.ValueOf(r)
+		for i := 2; i < ft.NumIn(); i++ {
+			paramType := ft.In(i)
+			baseType := paramType
+			if baseType.Kind() == reflect.Ptr {
+				baseType = baseType.Elem()
+			}
+			obj, err := scope.GetServiceContext(r.Context(), baseType)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			value := reflect.ValueOf(*obj)
+			if paramType.Kind() == reflect.Struct {
+				value = value.Elem()
+			}
+			args[i] = value
+		}
+
+		results := fv.Call(args)
+		if len(results) == 1 && !results[0].IsNil() && !rw.written {
+			http.Error(w, results[0].Interface().(error).Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// responseWriter 记录是否已经写入了响应
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush 实现 http.Flusher，原始的 ResponseWriter 不支持时忽略
+func (w *responseWriter) Flush() {
+	w.written = true
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap 返回原始的 ResponseWriter，供 http.ResponseController 使用
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// hijackResponseWriter 原始的 ResponseWriter 实现了 http.Hijacker 时使用，如 WebSocket 升级
+type hijackResponseWriter struct {
+	*responseWriter
+}
+
+// Hijack 接管连接后不能再写入错误响应
+func (w hijackResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.written = true
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// pushResponseWriter 原始的 ResponseWriter 实现了 http.Pusher 时使用
+type pushResponseWriter struct {
+	*responseWriter
+}
+
+func (w pushResponseWriter) Push(target string, opts *http.PushOptions) error {
+	return w.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// 包装 w 以记录是否已经写入了响应，并保留 w 实现的 http.Hijacker、http.Pusher 接口
+func wrapResponseWriter(w http.ResponseWriter) (http.ResponseWriter, *responseWriter) {
+	rw := &responseWriter{ResponseWriter: w}
+	_, hijacker := w.(http.Hijacker)
+	_, pusher := w.(http.Pusher)
+	switch {
+	case hijacker && pusher:
+		return struct {
+			hijackResponseWriter
+			http.Pusher
+		}{hijackResponseWriter{rw}, pushResponseWriter{rw}}, rw
+	case hijacker:
+		return hijackResponseWriter{rw}, rw
+	case pusher:
+		return pushResponseWriter{rw}, rw
+	}
+	return rw, rw
+}
+
+// 检查函数处理器的签名
+func checkHandlerFunc(ft reflect.Type) error {
+	if ft.Kind() != reflect.Func {
+		return fmt.Errorf("[ %v ] is not a function", ft)
+	}
+	if ft.NumIn() < 2 || ft.In(0) != responseWriterType || ft.In(1) != requestPtrType {
+		return fmt.Errorf("handler [ %v ] must accept http.ResponseWriter and *http.Request as the first two parameters", ft)
+	}
+	for i := 2; i < ft.NumIn(); i++ {
+		paramType := ft.In(i)
+		switch {
+		case paramType.Kind() == reflect.Interface, paramType.Kind() == reflect.Struct:
+		case paramType.Kind() == reflect.Ptr && paramType.Elem().Kind() == reflect.Struct:
+		default:
+			return fmt.Errorf("parameter [ %v ] of handler [ %v ] cannot be injected, only interface, struct or struct pointer is supported", paramType, ft)
+		}
+	}
+	if ft.IsVariadic() {
+		return fmt.Errorf("handler [ %v ] cannot be variadic", ft)
+	}
+	if ft.NumOut() > 1 || (ft.NumOut() == 1 && ft.Out(0) != errorType) {
+		return fmt.Errorf("handler [ %v ] can only return error", ft)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type IGreeter interface {
+	Greet(name string) string
+}
+
+type Greeter struct{}
+
+func (my *Greeter) Greet(name string) string { return "hello " + name }
+
+// 由容器实例化的处理器
+type GreetHandler struct {
+	Greeter IGreeter     `ioc:"true"`
+	Info    *RequestInfo `ioc:"true"`
+}
+
+func (my *GreetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(my.Greeter.Greet(my.Info.Path())))
+}
+
+func newMux(t *testing.T) http.Handler {
+	sc := &services.ServiceCollection{}
+	AddRequestServices(sc)
+	goioc.AddServiceOf[IGreeter, Greeter](sc, goioc.Singleton)
+	goioc.AddService[RequestInfo](sc, goioc.Scope)
+	goioc.AddService[GreetHandler](sc, goioc.Transient)
+	root := sc.Build()
+
+	mux := http.NewServeMux()
+	Handle[*GreetHandler](mux, "/handler")
+	HandleFunc(mux, "/func", func(w http.ResponseWriter, r *http.Request, greeter IGreeter, info *RequestInfo, ctx context.Context) {
+		if ctx != r.Context() {
+			t.Errorf("context is not injected")
+		}
+		_, _ = w.Write([]byte(greeter.Greet(info.Path())))
+	})
+	HandleFunc(mux, "/error", func(w http.ResponseWriter, r *http.Request, info RequestInfo) error {
+		return errors.New("failed " + info.Path())
+	})
+	HandleFunc(mux, "/partial", func(w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("accepted"))
+		return errors.New("failed after writing")
+	})
+	HandleFunc(mux, "/hijack", func(w http.ResponseWriter, r *http.Request, info *RequestInfo) error {
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			return errors.New("http.Hijacker is not forwarded")
+		}
+		conn, buf, err := hijacker.Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 7\r\nConnection: close\r\n\r\n" + info.Path())
+		_ = buf.Flush()
+		return errors.New("failed after hijacking")
+	})
+	return Middleware(root)(mux)
+}
+
+func TestHandle(t *testing.T) {
+	handler := newMux(t)
+	for path, expected := range map[string]string{
+		"/handler": "hello /handler",
+		"/func":    "hello /func",
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Body.String() != expected {
+			t.Errorf("%s: body = %q, expected %q", path, recorder.Body.String(), expected)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/error", nil))
+	if recorder.Code != http.StatusInternalServerError || recorder.Body.String() != "failed /error\n" {
+		t.Errorf("error is not returned: %d %q", recorder.Code, recorder.Body.String())
+	}
+	// 已经写入响应后返回的错误不会覆盖响应
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/partial", nil))
+	if recorder.Code != http.StatusAccepted || recorder.Body.String() != "accepted" {
+		t.Errorf("response is overwritten: %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+// 函数处理器可以接管连接，例如升级为 WebSocket
+func TestHandleHijack(t *testing.T) {
+	server := httptest.NewServer(newMux(t))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/hijack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "/hijack" {
+		t.Errorf("hijacked response = %d %q", resp.StatusCode, body)
+	}
+}
+
+func TestHandlerFuncSignature(t *testing.T) {
+	for _, f := range []interface{}{
+		"handler",
+		func(r *http.Request, w http.ResponseWriter) {},
+		func(w http.ResponseWriter, r *http.Request, id int) {},
+		func(w http.ResponseWriter, r *http.Request) string { return "" },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("invalid handler %T should panic", f)
+				}
+			}()
+			HandlerFunc(f)
+		}()
+	}
+}
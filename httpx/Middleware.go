@@ -0,0 +1,108 @@
+// Package httpx 将 goioc 与 net/http 集成，
+// 每个请求使用独立的作用域，请求结束时释放作用域中的 Scope 对象。
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"net/http"
+	"reflect"
+)
+
+var (
+	requestType = reflect.TypeOf(http.Request{})
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// scopeKey 请求上下文中保存作用域的键
+type scopeKey struct{}
+
+// scopeSetter 可以设置 Scope 对象的 IServiceProvider，*services.ServiceProvider 实现了此接口
+type scopeSetter interface {
+	SetScopeInstance(baseType reflect.Type, instance interface{}) error
+}
+
+// AddRequestServices 注册 *http.Request 和 context.Context 为 Scope 对象，
+// 实际的值由 Middleware 在每个请求的作用域中设置。
+// 注册后构建时的依赖检查、Describe 和 ExportGraph 可以识别依赖了请求的服务，
+// 不注册时 Middleware 也会在请求的作用域中提供这两个值
+func AddRequestServices(con goioc.IServiceCollection) {
+	con.AddServiceHandler(goioc.Scope, requestType, func(provider goioc.IServiceProvider) interface{} {
+		panic("*http.Request is only available in a request scope created by httpx.Middleware")
+	})
+	con.AddServiceHandler(goioc.Scope, contextType, func(provider goioc.IServiceProvider) interface{} {
+		panic("context.Context is only available in a request scope created by httpx.Middleware")
+	})
+}
+
+// Middleware 为每个请求创建 root 的作用域并保存到请求上下文中，
+// 作用域中的 *http.Request 和 context.Context 为当前请求及其上下文，
+// 处理结束（包括 panic）时释放作用域
+func Middleware(root goioc.IServiceProvider) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := root.CreateScope()
+			defer scope.Dispose()
+
+			ctx := context.WithValue(r.Context(), scopeKey{}, scope)
+			r = r.WithContext(ctx)
+			if err := setRequest(scope, r); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// 在作用域中设置当前请求及其上下文
+func setRequest(scope goioc.IServiceProvider, r *http.Request) error {
+	setter, ok := scope.(scopeSetter)
+	if !ok {
+		return fmt.Errorf("provider [ %T ] does not support scope instances", scope)
+	}
+	if err := setter.SetScopeInstance(requestType, r); err != nil {
+		return err
+	}
+	return setter.SetScopeInstance(contextType, r.Context())
+}
+
+// FromContext 获取请求上下文中的作用域，不在 Middleware 中时返回 nil
+func FromContext(ctx context.Context) goioc.IServiceProvider {
+	scope, _ := ctx.Value(scopeKey{}).(goioc.IServiceProvider)
+	return scope
+}
+
+// Scope 获取请求的作用域，不在 Middleware 中时返回 nil
+func Scope(r *http.Request) goioc.IServiceProvider {
+	return FromContext(r.Context())
+}
+
+// Get 从请求的作用域中获取对象，T 可以是接口或结构体指针，
+// 获取对象时使用请求的上下文
+func Get[T any](r *http.Request) (T, error) {
+	var result T
+	scope := Scope(r)
+	if scope == nil {
+		return result, fmt.Errorf("request is not in a scope created by httpx.Middleware")
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	baseType := t
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		baseType = t.Elem()
+	} else if t.Kind() != reflect.Interface {
+		// 容器返回的是结构体指针，结构体值类型无法通过类型断言得到
+		return result, fmt.Errorf("[ %v ] is not an interface or struct pointer", t)
+	}
+	obj, err := scope.GetServiceContext(r.Context(), baseType)
+	if err != nil {
+		return result, err
+	}
+	result, ok := (*obj).(T)
+	if !ok {
+		return result, fmt.Errorf("[ %T ] is not [ %v ]", *obj, t)
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package httpx
+
+import (
+	"context"
+	"github.com/whuanle/goioc"
+	"github.com/whuanle/goioc/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 每个请求一个实例
+type RequestInfo struct {
+	Request  *http.Request   `ioc:"true"`
+	Context  context.Context `ioc:"true"`
+	Disposed bool
+}
+
+func (my *RequestInfo) Path() string { return my.Request.URL.Path }
+
+func (my *RequestInfo) Dispose() { my.Disposed = true }
+
+func TestMiddleware(t *testing.T) {
+	sc := &services.ServiceCollection{}
+	AddRequestServices(sc)
+	goioc.AddService[RequestInfo](sc, goioc.Scope)
+	root := sc.Build()
+
+	var infos []*RequestInfo
+	handler := Middleware(root)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := Get[*RequestInfo](r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := Get[*RequestInfo](r)
+		if info != again {
+			t.Errorf("scope instance is not shared within a request")
+		}
+		if info.Request != r || info.Context != r.Context() {
+			t.Errorf("request is not injected")
+		}
+		infos = append(infos, info)
+		if _, err := Get[RequestInfo](r); err == nil || err.Error() != "[ httpx.RequestInfo ] is not an interface or struct pointer" {
+			t.Errorf("struct value should be rejected, got %v", err)
+		}
+		if r.URL.Path == "/panic" {
+			panic("handler")
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/a", nil))
+	func() {
+		defer func() { recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	}()
+
+	if len(infos) != 2 || infos[0] == infos[1] || infos[0].Path() != "/a" || infos[1].Path() != "/panic" {
+		t.Fatalf("each request should have its own scope")
+	}
+	if !infos[0].Disposed || !infos[1].Disposed {
+		t.Errorf("request scope is not disposed")
+	}
+
+	if _, err := Get[*RequestInfo](httptest.NewRequest("GET", "/", nil)); err == nil {
+		t.Errorf("request outside the middleware should fail")
+	}
+	if _, err := root.GetService(requestType); err == nil {
+		t.Errorf("request should not be available outside a request scope")
+	}
+}
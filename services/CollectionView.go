@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"reflect"
+)
+
+// collectionView 是 ServiceCollection 的视图，
+// 通过它注册的服务会附加条件和所属模块，其它操作转发给 ServiceCollection
+type collectionView struct {
+	parent *ServiceCollection
+	// 注册条件，为 nil 时是普通注册
+	condition goioc.Condition
+	// 所属模块
+	module string
+	// 模块导出的类型，为 nil 时模块中的服务全部导出
+	exports map[reflect.Type]bool
+}
+
+func (c *collectionView) AddService(lifetime goioc.ServiceLifetime, t reflect.Type) {
+	checkStructType(t)
+	c.addAny(lifetime, t, t, nil)
+}
+
+func (c *collectionView) AddServiceHandler(lifetime goioc.ServiceLifetime, t reflect.Type, f func(provider goioc.IServiceProvider) interface{}) {
+	checkBaseType(t)
+	c.addAny(lifetime, t, t, f)
+}
+
+func (c *collectionView) AddServiceOf(lifetime goioc.ServiceLifetime, baseType reflect.Type, serviceType reflect.Type) {
+	checkBaseType(baseType)
+	checkStructType(serviceType)
+	c.addAny(lifetime, baseType, serviceType, nil)
+}
+
+func (c *collectionView) AddServiceHandlerOf(
+	lifetime goioc.ServiceLifetime,
+	baseType reflect.Type,
+	serviceType reflect.Type,
+	f func(provider goioc.IServiceProvider) interface{}) {
+	checkBaseType(baseType)
+	checkBaseType(serviceType)
+	c.addAny(lifetime, baseType, serviceType, f)
+}
+
+// When 返回同时满足当前条件和 condition 的视图
+func (c *collectionView) When(condition goioc.Condition) goioc.IServiceCollection {
+	current := c.condition
+	if current != nil {
+		next := condition
+		condition = func(ctx goioc.IConditionContext) bool {
+			return current(ctx) && next(ctx)
+		}
+	}
+	return &collectionView{
+		parent:    c.parent,
+		condition: condition,
+		module:    c.module,
+		exports:   c.exports,
+	}
+}
+
+func (c *collectionView) AddModule(module goioc.IModule) {
+	c.parent.AddModule(module)
+}
+
+func (c *collectionView) SetProfile(profile string) {
+	c.parent.SetProfile(profile)
+}
+
+func (c *collectionView) Profile() string {
+	return c.parent.Profile()
+}
+
+func (c *collectionView) Descriptors() []goioc.ServiceDescriptor {
+	return c.parent.Descriptors()
+}
+
+func (c *collectionView) Describe() ([]byte, error) {
+	return c.parent.Describe()
+}
+
+func (c *collectionView) CopyTo() goioc.IServiceCollection {
+	return c.parent.CopyTo()
+}
+
+func (c *collectionView) Build() goioc.IServiceProvider {
+	return c.parent.Build()
+}
+
+func (c *collectionView) BuildWithOptions(options goioc.BuildOptions) (goioc.IServiceProvider, error) {
+	return c.parent.BuildWithOptions(options)
+}
+
+func (c *collectionView) addAny(
+	lifetime goioc.ServiceLifetime,
+	baseType reflect.Type,
+	serviceType reflect.Type,
+	f func(provider goioc.IServiceProvider) interface{}) {
+	descriptor := newDescriptor(lifetime, baseType, serviceType, f)
+	descriptor.Source = callerSource()
+	descriptor.Module = c.module
+	descriptor.Private = c.exports != nil && !c.exports[baseType]
+	if c.condition == nil {
+		c.parent.add(descriptor)
+		return
+	}
+	descriptor.Condition = c.condition
+	c.parent.addConditional(descriptor)
+}
+
+// conditionContext 即 goioc.IConditionContext 的实现
+type conditionContext struct {
+	collection *ServiceCollection
+	// 当前已经生效的注册
+	descriptors map[reflect.Type]goioc.ServiceDescriptor
+	// 还没有评估的条件注册的类型及数量，这些类型的注册可能还会被覆盖
+	pending map[reflect.Type]int
+	// 实例化配置时使用的单例管理器及失败策略，
+	// 构建时是 collection 本身，配置与构建得到的 Provider 共享同一个实例
+	singletons *ServiceCollection
+	policy     goioc.FailurePolicy
+	// 已经实例化的配置，一次评估中只实例化一次，配置的注册被覆盖时重新实例化
+	configuration goioc.IConfiguration
+	resolved      bool
+	// 实例化配置时的错误，会作为构建错误返回
+	err error
+}
+
+func (c *conditionContext) Profile() string {
+	return c.collection.Profile()
+}
+
+// Configuration 使用当前生效的注册实例化配置，失败时返回 nil 并记录错误。
+// 配置及其依赖的 Singleton 对象通过单例管理器获取，之后不会再被覆盖的注册会被提前登记；
+// 配置的注册还可能被之后的条件注册覆盖时，配置会单独实例化，不与 Provider 共享
+func (c *conditionContext) Configuration() goioc.IConfiguration {
+	if c.resolved {
+		return c.configuration
+	}
+	c.resolved = true
+	descriptor, ok := c.descriptors[configurationType]
+	if !ok {
+		return nil
+	}
+	for t, d := range c.descriptors {
+		if d.Lifetime == goioc.Singleton && c.pending[t] == 0 {
+			c.singletons.registerSingletonInstance(d, c.policy)
+		}
+	}
+	provider := newServiceProvider(c.descriptors, goioc.BuildOptions{FailurePolicy: c.policy}, c.singletons, newTracker())
+	r := resolveContext{ctx: context.Background(), lifetime: goioc.Transient, module: descriptor.Module}
+
+	var obj interface{}
+	var err error
+	if descriptor.Lifetime == goioc.Singleton && c.pending[configurationType] > 0 {
+		obj, err = construct(provider, r.child(descriptor), descriptor.InitHandler)
+	} else {
+		var value *interface{}
+		if value, err = getService(provider, r, configurationType); err == nil {
+			obj = *value
+		}
+	}
+	if err != nil {
+		if c.err == nil {
+			c.err = withSource(fmt.Errorf("cannot evaluate conditions, configuration failed: %w", err), descriptor)
+		}
+		return nil
+	}
+	c.configuration, _ = obj.(goioc.IConfiguration)
+	return c.configuration
+}
+
+// 条件注册 t 已经评估，生效时 applied 为 true，配置的注册被覆盖时之后重新实例化配置
+func (c *conditionContext) evaluated(t reflect.Type, applied bool) {
+	c.pending[t]--
+	if applied && t == configurationType {
+		c.resolved = false
+		c.configuration = nil
+	}
+}
+
+func (c *conditionContext) Has(t reflect.Type) bool {
+	_, ok := c.descriptors[t]
+	return ok
+}
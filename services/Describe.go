@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/whuanle/goioc"
+	"reflect"
+)
+
+// 工厂类型
+const (
+	factoryReflection = "reflection"
+	factoryHandler    = "handler"
+)
+
+// containerDescription Describe 输出的 JSON 文档
+type containerDescription struct {
+	Services []serviceDescription `json:"services"`
+}
+
+// serviceDescription 一个注册的描述
+type serviceDescription struct {
+	BaseType     string   `json:"baseType"`
+	ServiceType  string   `json:"serviceType"`
+	Name         string   `json:"name"`
+	Lifetime     string   `json:"lifetime"`
+	Factory      string   `json:"factory"`
+	Module       string   `json:"module,omitempty"`
+	Private      bool     `json:"private,omitempty"`
+	Dependencies []string `json:"dependencies"`
+	Source       string   `json:"source,omitempty"`
+	// 实例是否已经创建，只有 Provider 会输出
+	Created *bool `json:"created,omitempty"`
+}
+
+// Describe 以 JSON 描述所有生效的注册，按接口类型名称排序，
+// 相同的注册总是输出相同的内容，可以用于比较不同版本之间的差异
+func (s *ServiceCollection) Describe() ([]byte, error) {
+	descriptors, _, err := s.resolveDescriptors(nil)
+	if err != nil {
+		return nil, err
+	}
+	return describe(descriptors, nil)
+}
+
+// Describe 以 JSON 描述当前 Provider 中的所有注册，以及实例是否已经创建
+func (s *ServiceProvider) Describe() ([]byte, error) {
+	s.lock.RLock()
+	descriptors := make(map[reflect.Type]goioc.ServiceDescriptor, len(s.descriptors))
+	for t, descriptor := range s.descriptors {
+		descriptors[t] = descriptor
+	}
+	s.lock.RUnlock()
+	return describe(descriptors, s.created)
+}
+
+// 实例是否已经创建，Transient 对象每次都会重新创建，总是返回 false；
+// 不会等待正在实例化的 Singleton，可以在工厂函数和 Hooks 中调用
+func (s *ServiceProvider) created(descriptor goioc.ServiceDescriptor) bool {
+	switch descriptor.Lifetime {
+	case goioc.Scope:
+		return descriptor.ScopeInstance != nil
+	case goioc.Singleton:
+		singleton := s.serviceCollection.singletonDescriptors[descriptor.BaseType]
+		if singleton == nil {
+			return false
+		}
+		return singleton.created.Load()
+	}
+	return false
+}
+
+// created 为 nil 时不输出实例是否已经创建
+func describe(descriptors map[reflect.Type]goioc.ServiceDescriptor, created func(goioc.ServiceDescriptor) bool) ([]byte, error) {
+	types := make(map[reflect.Type]bool, len(descriptors))
+	for t := range descriptors {
+		types[t] = true
+	}
+
+	document := containerDescription{Services: []serviceDescription{}}
+	for _, t := range sortedTypes(types) {
+		descriptor := descriptors[t]
+		description := serviceDescription{
+			BaseType:     descriptor.BaseType.String(),
+			ServiceType:  descriptor.ServiceType.String(),
+			Name:         descriptor.Name,
+			Lifetime:     descriptor.Lifetime.String(),
+			Factory:      factoryHandler,
+			Module:       descriptor.Module,
+			Private:      descriptor.Private,
+			Dependencies: []string{},
+			Source:       descriptor.Source,
+		}
+		if descriptor.Reflection {
+			description.Factory = factoryReflection
+		}
+		for _, dep := range dependencies(descriptor.ServiceType) {
+			description.Dependencies = append(description.Dependencies, dep.String())
+		}
+		if created != nil {
+			value := created(descriptor)
+			description.Created = &value
+		}
+		document.Services = append(document.Services, description)
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
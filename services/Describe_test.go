@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/whuanle/goioc"
+	"testing"
+	"time"
+)
+
+func TestDescribe(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddService[Animal](sc, goioc.Transient)
+	goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		return &Dog{}
+	})
+
+	first, err := sc.Describe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := sc.Describe()
+	if string(first) != string(second) {
+		t.Errorf("describe is not stable")
+	}
+
+	var document containerDescription
+	if err := json.Unmarshal(first, &document); err != nil {
+		t.Fatal(err)
+	}
+	if len(document.Services) != 2 {
+		t.Fatalf("services = %d, expected 2", len(document.Services))
+	}
+	animal, dog := document.Services[0], document.Services[1]
+	if animal.BaseType != "services.Animal" || animal.Factory != "reflection" || animal.Lifetime != "Transient" ||
+		len(animal.Dependencies) != 1 || animal.Dependencies[0] != "services.IAnimal" || animal.Created != nil {
+		t.Errorf("unexpected description: %+v", animal)
+	}
+	if dog.BaseType != "services.IAnimal" || dog.ServiceType != "services.Dog" || dog.Factory != "handler" || dog.Lifetime != "Singleton" {
+		t.Errorf("unexpected description: %+v", dog)
+	}
+
+	p := sc.Build()
+	describeCreated := func() bool {
+		data, err := p.Describe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var document containerDescription
+		if err := json.Unmarshal(data, &document); err != nil {
+			t.Fatal(err)
+		}
+		return *document.Services[1].Created
+	}
+	if describeCreated() {
+		t.Errorf("singleton should not be created before it is resolved")
+	}
+	goioc.GetS[Animal](p)
+	if !describeCreated() {
+		t.Errorf("singleton should be created after it is resolved")
+	}
+}
+
+func TestDescribeDuringConstruction(t *testing.T) {
+	var p goioc.IServiceProvider
+	described := 0
+	describe := func() {
+		if _, err := p.Describe(); err != nil {
+			t.Error(err)
+		}
+		described++
+	}
+
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		describe()
+		return &Dog{}
+	})
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{Hooks: goioc.Hooks{
+		OnCreated: func(event goioc.ResolveEvent, instance interface{}, duration time.Duration) {
+			describe()
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		goioc.GetI[IAnimal](p)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Describe is blocked by the singleton under construction")
+	}
+	if described != 2 {
+		t.Errorf("described = %d, expected 2", described)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"github.com/whuanle/goioc"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Diagnostics 容器的运行状态，用于排查问题
+type Diagnostics struct {
+	// 所有注册的服务，按接口类型名称排序
+	Services []ServiceState `json:"services"`
+	// 通过 CreateScope 创建且还没有 Dispose 的作用域，按创建顺序排序
+	Scopes []ScopeState `json:"scopes"`
+}
+
+// ServiceState 服务的运行状态
+type ServiceState struct {
+	BaseType    string `json:"baseType"`
+	ServiceType string `json:"serviceType"`
+	Lifetime    string `json:"lifetime"`
+	// 当前 Provider 中实例是否已经创建，Transient 对象总是为 false
+	Created bool `json:"created"`
+	// 在所有作用域中创建实例的次数
+	Count int `json:"count"`
+	// 最后一次创建实例的时间和耗时
+	LastCreated  time.Time     `json:"lastCreated"`
+	LastDuration time.Duration `json:"lastDuration"`
+}
+
+// ScopeState 作用域的运行状态
+type ScopeState struct {
+	Id      int64     `json:"id"`
+	Created time.Time `json:"created"`
+}
+
+// tracker 记录实例化耗时、指标和活动的作用域，
+// 由同一次 Build 得到的 Provider 及其所有作用域共享
+type tracker struct {
+	lock     sync.Mutex
+	stats    map[reflect.Type]*stats
+	scopes   map[int64]time.Time
+	nextId   int64
+	disposed int64
+}
+
+// stats 一个类型的统计
+type stats struct {
+	resolutions int64
+	failures    int64
+	// 实例化次数，最后一次实例化的时间和耗时
+	count    int
+	last     time.Time
+	duration time.Duration
+	// 实例化耗时的总和及分布，buckets[i] 是耗时不超过 durationBuckets[i] 的次数
+	sum     time.Duration
+	buckets []int64
+}
+
+func newTracker() *tracker {
+	return &tracker{
+		stats:  map[reflect.Type]*stats{},
+		scopes: map[int64]time.Time{},
+	}
+}
+
+// 获取类型的统计，调用时需要持有锁
+func (t *tracker) get(baseType reflect.Type) *stats {
+	item := t.stats[baseType]
+	if item == nil {
+		item = &stats{buckets: make([]int64, len(durationBuckets))}
+		t.stats[baseType] = item
+	}
+	return item
+}
+
+// 记录一次获取对象
+func (t *tracker) resolving(baseType reflect.Type) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.get(baseType).resolutions++
+}
+
+// 记录一次获取对象失败
+func (t *tracker) failed(baseType reflect.Type) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.get(baseType).failures++
+}
+
+// 记录一次实例化
+func (t *tracker) created(baseType reflect.Type, start time.Time, duration time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	item := t.get(baseType)
+	item.count++
+	item.last = start
+	item.duration = duration
+	item.sum += duration
+	for i, bound := range durationBuckets {
+		if duration <= bound {
+			item.buckets[i]++
+		}
+	}
+}
+
+// 记录一次释放实例
+func (t *tracker) dispose() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.disposed++
+}
+
+// 记录一个新的作用域，返回作用域编号
+func (t *tracker) addScope() int64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.nextId++
+	t.scopes[t.nextId] = time.Now()
+	return t.nextId
+}
+
+func (t *tracker) removeScope(id int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.scopes, id)
+}
+
+// Diagnostics 获取容器的运行状态
+func (s *ServiceProvider) Diagnostics() Diagnostics {
+	s.lock.RLock()
+	descriptors := make(map[reflect.Type]goioc.ServiceDescriptor, len(s.descriptors))
+	for t, descriptor := range s.descriptors {
+		descriptors[t] = descriptor
+	}
+	s.lock.RUnlock()
+
+	types := make(map[reflect.Type]bool, len(descriptors))
+	for t := range descriptors {
+		types[t] = true
+	}
+	created := make(map[reflect.Type]bool, len(descriptors))
+	for t, descriptor := range descriptors {
+		created[t] = s.created(descriptor)
+	}
+
+	s.tracker.lock.Lock()
+	defer s.tracker.lock.Unlock()
+
+	result := Diagnostics{Services: []ServiceState{}, Scopes: []ScopeState{}}
+	for _, t := range sortedTypes(types) {
+		descriptor := descriptors[t]
+		item := s.tracker.stats[t]
+		if item == nil {
+			item = &stats{}
+		}
+		result.Services = append(result.Services, ServiceState{
+			BaseType:     descriptor.BaseType.String(),
+			ServiceType:  descriptor.ServiceType.String(),
+			Lifetime:     descriptor.Lifetime.String(),
+			Created:      created[t],
+			Count:        item.count,
+			LastCreated:  item.last,
+			LastDuration: item.duration,
+		})
+	}
+	for id, at := range s.tracker.scopes {
+		result.Scopes = append(result.Scopes, ScopeState{Id: id, Created: at})
+	}
+	sort.Slice(result.Scopes, func(i, j int) bool {
+		return result.Scopes[i].Id < result.Scopes[j].Id
+	})
+	return result
+}
+
+// Graph 导出当前 Provider 中所有注册的依赖图
+func (s *ServiceProvider) Graph(format GraphFormat) (string, error) {
+	s.lock.RLock()
+	types := make(map[reflect.Type]bool, len(s.descriptors))
+	for t := range s.descriptors {
+		types[t] = true
+	}
+	descriptors := make([]goioc.ServiceDescriptor, 0, len(types))
+	for _, t := range sortedTypes(types) {
+		descriptors = append(descriptors, s.descriptors[t])
+	}
+	s.lock.RUnlock()
+	return exportGraph(descriptors, format)
+}
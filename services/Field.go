@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"github.com/whuanle/goioc"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+var configurationType = reflect.TypeOf((*goioc.IConfiguration)(nil)).Elem()
+
+// iocTag 是字段 `ioc` tag 的解析结果，
+// 格式为 `ioc:"true"`、`ioc:"config=Server:Port"`，可以追加 `,unexported`
+type iocTag struct {
+	// 是否需要注入
+	inject bool
+	// 从配置中注入值的键
+	config string
+	// 是否允许注入未导出字段
+	unexported bool
+}
+
+// 解析字段的 ioc tag
+func parseTag(field reflect.StructField) iocTag {
+	var tag iocTag
+	value, ok := field.Tag.Lookup("ioc")
+	if !ok {
+		return tag
+	}
+	parts := strings.Split(value, ",")
+	first := strings.TrimSpace(parts[0])
+	tag.inject = first == "true"
+	if strings.HasPrefix(first, "config=") {
+		tag.config = strings.TrimPrefix(first, "config=")
+	}
+	for _, option := range parts[1:] {
+		if strings.TrimSpace(option) == "unexported" {
+			tag.unexported = true
+		}
+	}
+	return tag
+}
+
+// 获取可以赋值的字段，
+// 未导出的字段只能通过 unsafe 绕过反射的只读限制，字段所在的结构体必须是可寻址的
+func settable(field reflect.Value) reflect.Value {
+	if field.CanSet() {
+		return field
+	}
+	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+}
+
+// 字段类型是否可以被注入，只支持接口、结构体和结构体指针
+func isInjectableType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface, reflect.Struct:
+		return true
+	case reflect.Ptr:
+		return t.Elem().Kind() == reflect.Struct
+	}
+	return false
+}
+
+// 字段类型是否可以从配置中注入
+func isConfigType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Ptr:
+		return isConfigType(t.Elem())
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// 匿名嵌入的结构体(或结构体指针)中是否有需要注入的字段，
+// visiting 用于避免 type A struct{ *A } 这类循环嵌入
+func hasInjectFields(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag := parseTag(field); tag.inject || tag.config != "" {
+			return true
+		}
+		if field.Anonymous && hasInjectFields(field.Type, visiting) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFields 在 Build 时检查结构体中需要注入的字段，
+// 对于 Go 反射规则下无法完成的注入，提前返回错误。
+func checkFields(t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return checkStructFields(t, t.Name(), map[reflect.Type]bool{})
+}
+
+func checkStructFields(t reflect.Type, path string, visiting map[reflect.Type]bool) error {
+	if visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldPath := path + "." + field.Name
+		tag := parseTag(field)
+		if tag.config != "" {
+			if !isConfigType(field.Type) {
+				return fmt.Errorf("field [ %s ] of type [ %v ] cannot be injected from configuration", fieldPath, field.Type)
+			}
+			if !field.IsExported() && !tag.unexported {
+				return fmt.Errorf("field [ %s ] is unexported, use `ioc:\"config=%s,unexported\"` to allow injection", fieldPath, tag.config)
+			}
+			continue
+		}
+		if tag.inject {
+			if !isInjectableType(field.Type) {
+				return fmt.Errorf("field [ %s ] of type [ %v ] cannot be injected, only interface, struct or struct pointer is supported", fieldPath, field.Type)
+			}
+			if !field.IsExported() && !tag.unexported {
+				return fmt.Errorf("field [ %s ] is unexported, use `ioc:\"true,unexported\"` to allow injection", fieldPath)
+			}
+			continue
+		}
+		if !field.Anonymous || !hasInjectFields(field.Type, map[reflect.Type]bool{}) {
+			continue
+		}
+		// 嵌入的结构体指针为 nil 时需要先创建实例，反射不允许给未导出的嵌入字段赋值
+		if field.Type.Kind() == reflect.Ptr && !field.IsExported() {
+			return fmt.Errorf("embedded field [ %s ] is an unexported pointer and cannot be allocated, embed it by value or export the type", fieldPath)
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if err := checkStructFields(fieldType, fieldPath, visiting); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencies 获取结构体中所有需要注入的字段类型，
+// 包括匿名嵌入的结构体中的字段，字段类型是指针时返回指针指向的类型
+func dependencies(t reflect.Type) []reflect.Type {
+	var types []reflect.Type
+	if t.Kind() == reflect.Struct {
+		types = appendDependencies(types, t, map[reflect.Type]bool{})
+	}
+	return types
+}
+
+func appendDependencies(types []reflect.Type, t reflect.Type, visiting map[reflect.Type]bool) []reflect.Type {
+	if visiting[t] {
+		return types
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		tag := parseTag(field)
+		if tag.inject {
+			types = append(types, fieldType)
+			continue
+		}
+		if tag.config != "" {
+			if !containsType(types, configurationType) {
+				types = append(types, configurationType)
+			}
+			continue
+		}
+		if field.Anonymous && fieldType.Kind() == reflect.Struct {
+			types = appendDependencies(types, fieldType, visiting)
+		}
+	}
+	return types
+}
+
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, item := range types {
+		if item == t {
+			return true
+		}
+	}
+	return false
+}
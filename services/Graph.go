@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"github.com/whuanle/goioc"
+	"reflect"
+	"strings"
+)
+
+// GraphFormat 依赖图的输出格式
+type GraphFormat string
+
+const (
+	// GraphDOT Graphviz DOT 格式
+	GraphDOT GraphFormat = "dot"
+	// GraphMermaid Mermaid 流程图格式
+	GraphMermaid GraphFormat = "mermaid"
+)
+
+// graphEdge 依赖图中的一条依赖
+type graphEdge struct {
+	from, to int
+	// 依赖的类型没有注册
+	missing bool
+	// Singleton 依赖了 Scope 对象
+	captive bool
+}
+
+// graph 依赖图，nodes 中排在 descriptors 之后的是没有注册的类型
+type graph struct {
+	descriptors []goioc.ServiceDescriptor
+	nodes       []reflect.Type
+	edges       []graphEdge
+}
+
+// ExportGraph 导出 sc 中所有生效的注册以及它们通过字段注入形成的依赖关系，
+// 节点标注接口、实现和生命周期，没有注册的依赖和 Singleton 依赖 Scope 的边会被高亮
+func ExportGraph(sc goioc.IServiceCollection, format GraphFormat) (string, error) {
+	return exportGraph(sc.Descriptors(), format)
+}
+
+func exportGraph(descriptors []goioc.ServiceDescriptor, format GraphFormat) (string, error) {
+	g := newGraph(descriptors)
+	switch format {
+	case GraphDOT:
+		return g.dot(), nil
+	case GraphMermaid:
+		return g.mermaid(), nil
+	}
+	return "", fmt.Errorf("unsupported graph format [ %s ]", format)
+}
+
+func newGraph(descriptors []goioc.ServiceDescriptor) *graph {
+	g := &graph{descriptors: descriptors}
+	index := map[reflect.Type]int{}
+	for i, descriptor := range descriptors {
+		index[descriptor.BaseType] = i
+		g.nodes = append(g.nodes, descriptor.BaseType)
+	}
+	held := singletonHeld(descriptors, index)
+	for i, descriptor := range descriptors {
+		for _, dep := range dependencies(descriptor.ServiceType) {
+			to, ok := index[dep]
+			if !ok {
+				to = len(g.nodes)
+				index[dep] = to
+				g.nodes = append(g.nodes, dep)
+			}
+			edge := graphEdge{from: i, to: to, missing: to >= len(descriptors)}
+			if !edge.missing {
+				edge.captive = held[i] && descriptors[to].Lifetime == goioc.Scope
+			}
+			g.edges = append(g.edges, edge)
+		}
+	}
+	return g
+}
+
+// 计算被 Singleton 持有的节点，即 Singleton 本身，以及 Singleton 直接或经过 Transient 间接依赖的 Transient
+func singletonHeld(descriptors []goioc.ServiceDescriptor, index map[reflect.Type]int) []bool {
+	held := make([]bool, len(descriptors))
+	var visit func(i int)
+	visit = func(i int) {
+		for _, dep := range dependencies(descriptors[i].ServiceType) {
+			to, ok := index[dep]
+			if !ok || held[to] || descriptors[to].Lifetime != goioc.Transient {
+				continue
+			}
+			held[to] = true
+			visit(to)
+		}
+	}
+	for i, descriptor := range descriptors {
+		if descriptor.Lifetime == goioc.Singleton {
+			held[i] = true
+			visit(i)
+		}
+	}
+	return held
+}
+
+// 节点的各行标签，记录了注册位置时最后一行为注册位置，没有注册的类型只有类型名称
+func (g *graph) label(i int) []string {
+	if i >= len(g.descriptors) {
+		return []string{g.nodes[i].String(), "missing"}
+	}
+	descriptor := g.descriptors[i]
+	labels := []string{descriptor.BaseType.String(), descriptor.ServiceType.String(), descriptor.Lifetime.String()}
+	if descriptor.Source != "" {
+		labels = append(labels, descriptor.Source)
+	}
+	return labels
+}
+
+func (g *graph) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph goioc {\n")
+	b.WriteString("\tnode [shape=box];\n")
+	for i := range g.nodes {
+		label := strings.Join(escapeLabels(g.label(i), `"`, `\"`), `\n`)
+		if i >= len(g.descriptors) {
+			fmt.Fprintf(&b, "\tn%d [label=\"%s\", color=red, fontcolor=red, style=dashed];\n", i, label)
+			continue
+		}
+		fmt.Fprintf(&b, "\tn%d [label=\"%s\"];\n", i, label)
+	}
+	for _, edge := range g.edges {
+		switch {
+		case edge.missing:
+			fmt.Fprintf(&b, "\tn%d -> n%d [color=red, style=dashed, label=\"missing\"];\n", edge.from, edge.to)
+		case edge.captive:
+			fmt.Fprintf(&b, "\tn%d -> n%d [color=orange, label=\"captive\"];\n", edge.from, edge.to)
+		default:
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", edge.from, edge.to)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (g *graph) mermaid() string {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for i := range g.nodes {
+		label := strings.Join(escapeLabels(g.label(i), `"`, "#quot;"), "<br/>")
+		fmt.Fprintf(&b, "\tn%d[\"%s\"]\n", i, label)
+	}
+	var captive []string
+	for i, edge := range g.edges {
+		switch {
+		case edge.missing:
+			fmt.Fprintf(&b, "\tn%d -.->|missing| n%d\n", edge.from, edge.to)
+		case edge.captive:
+			fmt.Fprintf(&b, "\tn%d -->|captive| n%d\n", edge.from, edge.to)
+			captive = append(captive, fmt.Sprint(i))
+		default:
+			fmt.Fprintf(&b, "\tn%d --> n%d\n", edge.from, edge.to)
+		}
+	}
+	if len(g.nodes) > len(g.descriptors) {
+		b.WriteString("\tclassDef missing stroke:red,color:red,stroke-dasharray:5\n")
+		for i := len(g.descriptors); i < len(g.nodes); i++ {
+			fmt.Fprintf(&b, "\tclass n%d missing\n", i)
+		}
+	}
+	if len(captive) > 0 {
+		fmt.Fprintf(&b, "\tlinkStyle %s stroke:orange\n", strings.Join(captive, ","))
+	}
+	return b.String()
+}
+
+func escapeLabels(labels []string, old, new string) []string {
+	result := make([]string, len(labels))
+	for i, label := range labels {
+		result[i] = strings.ReplaceAll(label, old, new)
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"github.com/whuanle/goioc"
+	"strings"
+	"testing"
+)
+
+func TestExportGraph(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Animal](sc, goioc.Singleton)
+	goioc.AddService[Keeper](sc, goioc.Transient)
+
+	dot, err := ExportGraph(sc, GraphDOT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`n0 [label="services.IAnimal\nservices.Dog\nScope\nservices/Graph_test.go:`,
+		`n1 -> n0 [color=orange, label="captive"];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("dot does not contain %s:\n%s", expected, dot)
+		}
+	}
+
+	mermaid, err := ExportGraph(sc, GraphMermaid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`n1["services.Animal<br/>services.Animal<br/>Singleton<br/>services/Graph_test.go:`,
+		`n1 -->|captive| n0`,
+		`linkStyle 0 stroke:orange`,
+	} {
+		if !strings.Contains(mermaid, expected) {
+			t.Errorf("mermaid does not contain %s:\n%s", expected, mermaid)
+		}
+	}
+
+	if _, err := ExportGraph(sc, "svg"); err == nil {
+		t.Errorf("unsupported format should return an error")
+	}
+}
+
+func TestExportGraphTransitiveCaptive(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Keeper](sc, goioc.Transient)
+	goioc.AddService[Zoo](sc, goioc.Singleton)
+	goioc.AddService[Animal](sc, goioc.Transient)
+
+	dot, _ := ExportGraph(sc, GraphDOT)
+	// Zoo -> Keeper -> IAnimal，Keeper 被 Singleton 持有
+	if !strings.Contains(dot, `n1 -> n0 [color=orange, label="captive"];`) {
+		t.Errorf("transitive captive dependency is not highlighted:\n%s", dot)
+	}
+	if strings.Contains(dot, `n2 -> n1 [color=orange`) || strings.Contains(dot, `n3 -> n0 [color=orange`) {
+		t.Errorf("only dependencies held by a singleton are captive:\n%s", dot)
+	}
+}
+
+func TestExportGraphMissing(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddService[Animal](sc, goioc.Transient)
+
+	dot, _ := ExportGraph(sc, GraphDOT)
+	if !strings.Contains(dot, `n1 [label="services.IAnimal\nmissing", color=red`) || !strings.Contains(dot, `n0 -> n1 [color=red, style=dashed`) {
+		t.Errorf("missing dependency is not highlighted:\n%s", dot)
+	}
+	mermaid, _ := ExportGraph(sc, GraphMermaid)
+	if !strings.Contains(mermaid, "n0 -.->|missing| n1") || !strings.Contains(mermaid, "class n1 missing") {
+		t.Errorf("missing dependency is not highlighted:\n%s", mermaid)
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"github.com/whuanle/goioc"
+	"log/slog"
+	"reflect"
+)
+
+// override 被覆盖的注册
+type override struct {
+	previous goioc.ServiceDescriptor
+	current  goioc.ServiceDescriptor
+}
+
+// 日志中描述服务的属性
+func serviceAttrs(baseType reflect.Type, impl string, lifetime goioc.ServiceLifetime) []any {
+	return []any{
+		slog.String("service", baseType.String()),
+		slog.String("impl", impl),
+		slog.String("lifetime", lifetime.String()),
+	}
+}
+
+// 构建时记录被覆盖的注册，以及没有注册的依赖、Singleton 依赖 Scope 对象等不会导致构建失败的问题
+func (s *ServiceCollection) logBuild(logger *slog.Logger, descriptors []goioc.ServiceDescriptor) {
+	for _, item := range s.overrides {
+		attrs := serviceAttrs(item.current.BaseType, item.current.ServiceType.String(), item.current.Lifetime)
+		attrs = append(attrs,
+			slog.String("previous", item.previous.ServiceType.String()),
+			slog.String("source", item.current.Source),
+			slog.String("previousSource", item.previous.Source))
+		logger.Warn("registration overridden", attrs...)
+	}
+
+	g := newGraph(descriptors)
+	for _, edge := range g.edges {
+		descriptor := descriptors[edge.from]
+		attrs := serviceAttrs(descriptor.BaseType, descriptor.ServiceType.String(), descriptor.Lifetime)
+		attrs = append(attrs, slog.String("dependency", g.nodes[edge.to].String()), slog.String("source", descriptor.Source))
+		switch {
+		case edge.missing:
+			logger.Warn("dependency is not registered", attrs...)
+		case edge.captive:
+			logger.Warn("singleton depends on a scoped service", attrs...)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"bytes"
+	"github.com/whuanle/goioc"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Dispose 时 panic
+type BrokenConnection struct{}
+
+func (my *BrokenConnection) Dispose() { panic("broken") }
+
+func TestLogging(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Transient)
+	goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		return &Dog{}
+	})
+	goioc.AddService[Shop](sc, goioc.Transient)
+	goioc.AddService[BrokenConnection](sc, goioc.Scope)
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{Logger: logger, SlowFactoryThreshold: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goioc.GetI[IAnimal](p)
+	scope := p.CreateScope()
+	goioc.GetS[BrokenConnection](scope)
+	scope.Dispose()
+
+	output := buffer.String()
+	for _, expected := range []string{
+		`msg="registration overridden" service=services.IAnimal impl=services.Dog lifetime=Singleton previous=services.Dog`,
+		`msg="dependency is not registered" service=services.Shop impl=services.Shop lifetime=Transient dependency=services.IStore`,
+		`msg="singleton created" service=services.IAnimal impl=*services.Dog lifetime=Singleton duration=`,
+		`msg="slow factory" service=services.IAnimal`,
+		`msg="dispose failed" service=services.BrokenConnection impl=*services.BrokenConnection lifetime=Scope error="dispose panicked: broken"`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("log does not contain %s:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// 实例化耗时分布的上界
+var durationBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// Metrics 容器指标的快照，包括同一次 Build 得到的 Provider 及其所有作用域
+type Metrics struct {
+	// 按类型统计，按类型名称排序
+	Services []ServiceMetrics `json:"services"`
+	// 按生命周期统计的获取次数，没有注册的类型不计入
+	Resolutions map[string]int64 `json:"resolutions"`
+	// 创建的实例数量
+	Creations int64 `json:"creations"`
+	// 获取失败的次数
+	Failures int64 `json:"failures"`
+	// 还没有 Dispose 的作用域数量
+	LiveScopes int `json:"liveScopes"`
+	// 释放的实现了 IDispose 接口的实例数量
+	Disposed int64 `json:"disposed"`
+}
+
+// ServiceMetrics 一个类型的指标
+type ServiceMetrics struct {
+	Type string `json:"type"`
+	// 生命周期，类型没有注册时为空
+	Lifetime    string `json:"lifetime,omitempty"`
+	Resolutions int64  `json:"resolutions"`
+	Creations   int64  `json:"creations"`
+	Failures    int64  `json:"failures"`
+	// 实例化耗时的分布
+	FactoryDuration Histogram `json:"factoryDuration"`
+}
+
+// Histogram 耗时分布
+type Histogram struct {
+	Count int64         `json:"count"`
+	Sum   time.Duration `json:"sum"`
+	// 累计分布，每个桶是耗时不超过 UpperBound 的次数
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Bucket 耗时分布中的一个桶
+type Bucket struct {
+	UpperBound time.Duration `json:"upperBound"`
+	Count      int64         `json:"count"`
+}
+
+// Metrics 获取容器指标的快照
+func (s *ServiceProvider) Metrics() Metrics {
+	s.lock.RLock()
+	lifetimes := make(map[reflect.Type]goioc.ServiceLifetime, len(s.descriptors))
+	for t, descriptor := range s.descriptors {
+		lifetimes[t] = descriptor.Lifetime
+	}
+	s.lock.RUnlock()
+
+	s.tracker.lock.Lock()
+	defer s.tracker.lock.Unlock()
+
+	result := Metrics{
+		Services:    []ServiceMetrics{},
+		Resolutions: map[string]int64{},
+		LiveScopes:  len(s.tracker.scopes),
+		Disposed:    s.tracker.disposed,
+	}
+	types := make(map[reflect.Type]bool, len(s.tracker.stats))
+	for t := range s.tracker.stats {
+		types[t] = true
+	}
+	for _, t := range sortedTypes(types) {
+		item := s.tracker.stats[t]
+		metrics := ServiceMetrics{
+			Type:        t.String(),
+			Resolutions: item.resolutions,
+			Creations:   int64(item.count),
+			Failures:    item.failures,
+			FactoryDuration: Histogram{
+				Count:   int64(item.count),
+				Sum:     item.sum,
+				Buckets: make([]Bucket, len(durationBuckets)),
+			},
+		}
+		for i, bound := range durationBuckets {
+			metrics.FactoryDuration.Buckets[i] = Bucket{UpperBound: bound, Count: item.buckets[i]}
+		}
+		if lifetime, ok := lifetimes[t]; ok {
+			metrics.Lifetime = lifetime.String()
+			result.Resolutions[metrics.Lifetime] += item.resolutions
+		}
+		result.Creations += metrics.Creations
+		result.Failures += item.failures
+		result.Services = append(result.Services, metrics)
+	}
+	return result
+}
+
+// 已经发布到 expvar 的指标，键为名称
+var published sync.Map
+
+// metricsVar 即 expvar.Var 的实现，输出最后一次使用该名称构建的 Provider 的指标
+type metricsVar struct {
+	lock     sync.RWMutex
+	provider *ServiceProvider
+}
+
+func (v *metricsVar) String() string {
+	v.lock.RLock()
+	provider := v.provider
+	v.lock.RUnlock()
+	data, err := json.Marshal(provider.Metrics())
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+// 将 provider 的指标发布到 expvar，
+// 同一个名称多次发布时输出最后一次发布的 provider 的指标，
+// 名称已经被其它包发布时返回错误
+func publishMetrics(name string, provider *ServiceProvider) error {
+	value, loaded := published.LoadOrStore(name, &metricsVar{provider: provider})
+	v := value.(*metricsVar)
+	if loaded {
+		v.lock.Lock()
+		v.provider = provider
+		v.lock.Unlock()
+		return nil
+	}
+	if expvar.Get(name) != nil {
+		published.Delete(name)
+		return fmt.Errorf("expvar [ %s ] is already published", name)
+	}
+	expvar.Publish(name, v)
+	return nil
+}
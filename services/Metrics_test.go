@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"expvar"
+	"github.com/whuanle/goioc"
+	"reflect"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Keeper](sc, goioc.Transient)
+	goioc.AddService[Shop](sc, goioc.Transient)
+	goioc.AddService[Connection](sc, goioc.Scope)
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{MetricsName: "goioc_test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goioc.GetS[Keeper](p)
+	goioc.GetS[Keeper](p)
+	if _, err := p.GetService(reflect.TypeOf(Shop{})); err == nil {
+		t.Fatalf("missing dependency should fail")
+	}
+	scope := p.CreateScope()
+	goioc.GetS[Connection](scope)
+
+	metrics := p.(*ServiceProvider).Metrics()
+	if metrics.LiveScopes != 1 || metrics.Creations != 4 || metrics.Failures != 2 {
+		t.Errorf("unexpected metrics: %+v", metrics)
+	}
+	if metrics.Resolutions["Transient"] != 3 || metrics.Resolutions["Scope"] != 3 {
+		t.Errorf("resolutions = %v", metrics.Resolutions)
+	}
+	for _, service := range metrics.Services {
+		if service.Type == "services.Keeper" {
+			histogram := service.FactoryDuration
+			if service.Resolutions != 2 || service.Creations != 2 || histogram.Count != 2 ||
+				histogram.Buckets[len(histogram.Buckets)-1].Count != 2 {
+				t.Errorf("unexpected metrics: %+v", service)
+			}
+		}
+	}
+
+	scope.Dispose()
+	var published Metrics
+	if err := json.Unmarshal([]byte(expvar.Get("goioc_test").String()), &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.LiveScopes != 0 || published.Disposed != 1 {
+		t.Errorf("unexpected published metrics: %+v", published)
+	}
+
+	// 同一个名称重复构建不会 panic
+	if _, err := sc.BuildWithOptions(goioc.BuildOptions{MetricsName: "goioc_test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 名称已经被其它包使用时返回错误
+	expvar.NewInt("goioc_test_taken")
+	if _, err := sc.BuildWithOptions(goioc.BuildOptions{MetricsName: "goioc_test_taken"}); err == nil {
+		t.Errorf("publishing an existing expvar should fail")
+	}
+}
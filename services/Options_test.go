@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 基于 map 的配置，键为完整路径
+type mapConfiguration struct {
+	prefix string
+	values map[string]string
+}
+
+func (c *mapConfiguration) Get(key string) (string, bool) {
+	value, ok := c.values[strings.ToLower(c.prefix+key)]
+	return value, ok
+}
+
+func (c *mapConfiguration) GetSection(key string) goioc.IConfiguration {
+	return &mapConfiguration{prefix: c.prefix + key + ":", values: c.values}
+}
+
+func (c *mapConfiguration) Children() []string {
+	seen := map[string]bool{}
+	var children []string
+	for key := range c.values {
+		if !strings.HasPrefix(key, strings.ToLower(c.prefix)) {
+			continue
+		}
+		child := strings.SplitN(key[len(c.prefix):], ":", 2)[0]
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func newMapConfiguration(values map[string]string) *mapConfiguration {
+	lower := map[string]string{}
+	for key, value := range values {
+		lower[strings.ToLower(key)] = value
+	}
+	return &mapConfiguration{values: lower}
+}
+
+type DbOptions struct {
+	Host    string
+	Port    int           `default:"5432"`
+	Timeout time.Duration `default:"5s"`
+	Hosts   []string
+	Tags    []string `default:"a,b"`
+	Pool    struct {
+		Size int `config:"MaxSize" default:"10"`
+	}
+}
+
+type Repository struct {
+	Options goioc.IOptions[DbOptions] `ioc:"true"`
+}
+
+func TestConfigure(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{
+		"Db:Host":         "localhost",
+		"Db:Timeout":      "1m",
+		"Db:Hosts:0":      "a",
+		"Db:Hosts:1":      "b",
+		"Db:Hosts:10":     "c",
+		"Db:Pool:MaxSize": "20",
+	}))
+	goioc.Configure[DbOptions](sc, "Db")
+	goioc.AddService[Repository](sc, goioc.Transient)
+	p := sc.Build()
+
+	options := goioc.GetS[Repository](p).Options.Value()
+	if options.Host != "localhost" || options.Port != 5432 || options.Timeout != time.Minute {
+		t.Errorf("options are not bound: %+v", options)
+	}
+	if strings.Join(options.Hosts, ",") != "a,b,c" || strings.Join(options.Tags, ",") != "a,b" {
+		t.Errorf("slices are not bound: %+v", options)
+	}
+	if options.Pool.Size != 20 {
+		t.Errorf("nested options are not bound: %+v", options)
+	}
+}
+
+func TestConfigureError(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{
+		"Db:Port":    "port",
+		"Db:Timeout": "1 minute",
+	}))
+	goioc.Configure[DbOptions](sc, "Db")
+
+	// 配置错误时构建失败
+	_, err := sc.BuildWithOptions(goioc.BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "DbOptions.Port") || !strings.Contains(err.Error(), "DbOptions.Timeout") {
+		t.Errorf("bind errors are not reported together: %v", err)
+	}
+}
+
+type ServerOptions struct {
+	Host    string        `validate:"required"`
+	Port    int           `validate:"min=1,max=65535"`
+	Timeout time.Duration `validate:"min=1s"`
+	Hosts   []string      `validate:"min=1"`
+}
+
+func (o *ServerOptions) Validate() error {
+	if o.Host == "localhost" && o.Port == 80 {
+		return fmt.Errorf("port 80 is not allowed on localhost")
+	}
+	return nil
+}
+
+func TestValidateOptions(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{
+		"Server:Port":    "0",
+		"Server:Timeout": "1ms",
+	}))
+	goioc.Configure[ServerOptions](sc, "Server")
+
+	_, err := sc.BuildWithOptions(goioc.BuildOptions{})
+	var aggregate *goioc.AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 1 {
+		t.Fatalf("validation error is not returned: %v", err)
+	}
+	// 构建错误中包含 IOptions 返回的每个字段的错误
+	var fields *goioc.AggregateError
+	if !errors.As(aggregate.Errors[0], &fields) || len(fields.Errors) != 4 {
+		t.Fatalf("field errors are not returned: %v", aggregate.Errors[0])
+	}
+	for i, field := range []string{"Host", "Port", "Timeout", "Hosts"} {
+		if !strings.Contains(fields.Errors[i].Error(), "ServerOptions."+field) {
+			t.Errorf("field %s is not reported: %v", field, fields.Errors[i])
+		}
+	}
+}
+
+func TestValidatable(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{
+		"Server:Host":    "localhost",
+		"Server:Port":    "80",
+		"Server:Timeout": "1s",
+		"Server:Hosts":   "a",
+	}))
+	goioc.Configure[ServerOptions](sc, "Server")
+
+	_, err := sc.BuildWithOptions(goioc.BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "port 80") {
+		t.Errorf("IValidatable error is not returned: %v", err)
+	}
+}
+
+// 从配置中注入字段
+type Server struct {
+	Host    string        `ioc:"config=Server:Host"`
+	Port    int           `ioc:"config=Server:Port" default:"8080"`
+	Debug   bool          `ioc:"config=Server:Debug"`
+	Timeout time.Duration `ioc:"config=Server:Timeout" default:"30s"`
+	Hosts   []string      `ioc:"config=Server:Hosts"`
+	name    string        `ioc:"config=Server:Name,unexported"`
+}
+
+type BadServer struct {
+	Port int `ioc:"config=Server:Port"`
+}
+
+// 嵌入了 BadServer，错误信息中包含完整路径
+type BadGateway struct {
+	*BadServer
+}
+
+func TestInjectConfig(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{
+		"Server:Host":    "localhost",
+		"Server:Debug":   "true",
+		"Server:Hosts:0": "a",
+		"Server:Hosts:1": "b",
+		"Server:Name":    "api",
+	}))
+	goioc.AddService[Server](sc, goioc.Scope)
+	p := sc.Build()
+
+	server := goioc.GetS[Server](p)
+	if server.Host != "localhost" || server.Port != 8080 || !server.Debug || server.Timeout != 30*time.Second {
+		t.Errorf("config is not injected: %+v", server)
+	}
+	if len(server.Hosts) != 2 || server.name != "api" {
+		t.Errorf("config is not injected: %+v", server)
+	}
+}
+
+func TestInjectConfigError(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{
+		"Server:Port": "port",
+	}))
+	goioc.AddService[BadServer](sc, goioc.Scope)
+	p := sc.Build()
+
+	_, err := p.GetService(reflect.TypeOf((*BadServer)(nil)).Elem())
+	if err == nil || !strings.Contains(err.Error(), "BadServer.Port") {
+		t.Errorf("conversion error does not contain field path: %v", err)
+	}
+
+	goioc.AddService[BadGateway](sc, goioc.Scope)
+	p = sc.Build()
+	_, err = p.GetService(reflect.TypeOf((*BadGateway)(nil)).Elem())
+	if err == nil || !strings.Contains(err.Error(), "BadGateway.BadServer.Port") {
+		t.Errorf("conversion error does not contain full field path: %v", err)
+	}
+}
@@ -1,36 +1,68 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"github.com/whuanle/goioc"
+	"os"
 	"reflect"
+	"strings"
 	"sync"
 )
 
+// ProfileEnvironmentVariable 未调用 SetProfile 时，从此环境变量读取当前环境，
+// 环境变量不存在时为 goioc.Production
+const ProfileEnvironmentVariable = "GOIOC_PROFILE"
+
 // ServiceCollection 即 IServiceCollection 的实现
 type ServiceCollection struct {
 	// 服务描述
 	descriptors map[reflect.Type]goioc.ServiceDescriptor
+	// 服务注册顺序，重复注册的类型保持第一次注册的位置
+	order []reflect.Type
+	// 条件注册，按注册顺序在 Build 时评估
+	conditionals []goioc.ServiceDescriptor
+	// 被覆盖的注册，构建时记录日志
+	overrides []override
+	// 当前环境
+	profile string
+	// 已添加的模块，按添加顺序排列
+	modules []string
 	// single对象描述
 	singletonDescriptors map[reflect.Type]*SingletonDescriptor
 	// 当前在容器中类型数量
 	Count int
 }
 
-// 基础注入方法
+// 基础注入方法，f 为 nil 时通过反射实例化 serviceType
 func (s *ServiceCollection) addAny(
 	lifetime goioc.ServiceLifetime,
 	baseType reflect.Type,
 	serviceType reflect.Type,
 	f func(provider goioc.IServiceProvider) interface{}) {
-	descriptor := goioc.ServiceDescriptor{
+	descriptor := newDescriptor(lifetime, baseType, serviceType, f)
+	descriptor.Source = callerSource()
+	s.add(descriptor)
+}
+
+// 创建 ServiceDescriptor，f 为 nil 时通过反射实例化 serviceType
+func newDescriptor(
+	lifetime goioc.ServiceLifetime,
+	baseType reflect.Type,
+	serviceType reflect.Type,
+	f func(provider goioc.IServiceProvider) interface{}) goioc.ServiceDescriptor {
+	reflection := f == nil
+	if reflection {
+		f = getInitHandler(serviceType)
+	}
+	return goioc.ServiceDescriptor{
 		Name:        serviceType.Name(),
 		BaseType:    baseType,
 		ServiceType: serviceType,
 		Lifetime:    lifetime,
 		InitHandler: f,
+		Reflection:  reflection,
 	}
-	s.add(descriptor)
 }
 
 // 实现 IServiceCollection 接口
@@ -49,20 +81,19 @@ func checkBaseType(t reflect.Type) {
 	if t.Kind() == reflect.Interface || t.Kind() == reflect.Struct {
 		return
 	}
-	panic(fmt.Sprintf("[ %t ] is not an interface or struct", t))
+	panic(fmt.Sprintf("[ %v ] is not an interface or struct", t))
 }
 
 // 检查是否为结构体
 func checkStructType(t reflect.Type) {
 	if t.Kind() != reflect.Struct {
-		panic(fmt.Sprintf("[ %t ] is not an struct", t))
+		panic(fmt.Sprintf("[ %v ] is not an struct", t))
 	}
 }
 
 func (s *ServiceCollection) AddService(lifetime goioc.ServiceLifetime, t reflect.Type) {
 	checkStructType(t)
-	f := getInitHandler(t)
-	s.addAny(lifetime, t, t, f)
+	s.addAny(lifetime, t, t, nil)
 }
 
 func (s *ServiceCollection) AddServiceHandler(lifetime goioc.ServiceLifetime, t reflect.Type, f func(provider goioc.IServiceProvider) interface{}) {
@@ -73,8 +104,7 @@ func (s *ServiceCollection) AddServiceHandler(lifetime goioc.ServiceLifetime, t
 func (s *ServiceCollection) AddServiceOf(lifetime goioc.ServiceLifetime, baseType reflect.Type, serviceType reflect.Type) {
 	checkBaseType(baseType)
 	checkStructType(serviceType)
-	f := getInitHandler(serviceType)
-	s.addAny(lifetime, baseType, serviceType, f)
+	s.addAny(lifetime, baseType, serviceType, nil)
 }
 
 func (s *ServiceCollection) AddServiceHandlerOf(
@@ -87,6 +117,80 @@ func (s *ServiceCollection) AddServiceHandlerOf(
 	s.addAny(lifetime, baseType, serviceType, f)
 }
 
+func (s *ServiceCollection) When(condition goioc.Condition) goioc.IServiceCollection {
+	return &collectionView{parent: s, condition: condition}
+}
+
+func (s *ServiceCollection) AddModule(module goioc.IModule) {
+	s.addModule(module, nil)
+}
+
+// 添加模块，path 为正在添加的依赖链，用于检测循环依赖
+func (s *ServiceCollection) addModule(module goioc.IModule, path []string) {
+	name := module.Name()
+	for i, item := range path {
+		if item == name {
+			panic(fmt.Sprintf("module cycle detected: %s", strings.Join(append(path[i:], name), " -> ")))
+		}
+	}
+	if s.hasModule(name) {
+		return
+	}
+
+	path = append(path, name)
+	for _, dependency := range module.DependsOn() {
+		s.addModule(dependency, path)
+	}
+
+	// 依赖链上的模块可能已经通过其它路径添加
+	if s.hasModule(name) {
+		return
+	}
+	s.modules = append(s.modules, name)
+	module.ConfigureServices(&collectionView{parent: s, module: name, exports: moduleExports(module)})
+}
+
+// 获取模块导出的类型，模块没有实现 IExportingModule 时返回 nil
+func moduleExports(module goioc.IModule) map[reflect.Type]bool {
+	exporting, ok := module.(goioc.IExportingModule)
+	if !ok {
+		return nil
+	}
+	exports := map[reflect.Type]bool{}
+	for _, t := range exporting.Exports() {
+		exports[t] = true
+	}
+	return exports
+}
+
+func (s *ServiceCollection) hasModule(name string) bool {
+	for _, module := range s.modules {
+		if module == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Modules 按添加顺序返回所有模块名称
+func (s *ServiceCollection) Modules() []string {
+	return append([]string{}, s.modules...)
+}
+
+func (s *ServiceCollection) SetProfile(profile string) {
+	s.profile = profile
+}
+
+func (s *ServiceCollection) Profile() string {
+	if s.profile != "" {
+		return s.profile
+	}
+	if profile := os.Getenv(ProfileEnvironmentVariable); profile != "" {
+		return profile
+	}
+	return goioc.Production
+}
+
 // 私有方法
 
 // 添加一个 ServiceDescriptor
@@ -94,6 +198,11 @@ func (s *ServiceCollection) add(serviceDescriptor goioc.ServiceDescriptor) {
 	if s.descriptors == nil {
 		s.descriptors = make(map[reflect.Type]goioc.ServiceDescriptor)
 	}
+	if previous, ok := s.descriptors[serviceDescriptor.BaseType]; ok {
+		s.overrides = append(s.overrides, override{previous: previous, current: serviceDescriptor})
+	} else {
+		s.order = append(s.order, serviceDescriptor.BaseType)
+	}
 	s.descriptors[serviceDescriptor.BaseType] = serviceDescriptor
 	s.Count = len(s.descriptors)
 }
@@ -101,7 +210,7 @@ func (s *ServiceCollection) add(serviceDescriptor goioc.ServiceDescriptor) {
 func (s *ServiceCollection) get(baseType reflect.Type) *goioc.ServiceDescriptor {
 	sd, ok := s.descriptors[baseType]
 	if !ok {
-		panic(fmt.Sprintf("Type [ %t ] not found", baseType))
+		panic(fmt.Sprintf("Type [ %v ] not found", baseType))
 	}
 	return &sd
 }
@@ -109,35 +218,117 @@ func (s *ServiceCollection) get(baseType reflect.Type) *goioc.ServiceDescriptor
 // 移除一个 ServiceDescriptor
 func (s *ServiceCollection) remove(descriptor goioc.ServiceDescriptor) {
 	delete(s.descriptors, descriptor.BaseType)
+	for i, t := range s.order {
+		if t == descriptor.BaseType {
+			s.order = append(s.order[:i:i], s.order[i+1:]...)
+			break
+		}
+	}
 	s.Count = len(s.descriptors)
 }
 
 func (s *ServiceCollection) Build() goioc.IServiceProvider {
-	// 第一次使用时，初始化单例管理器
-	if s.singletonDescriptors == nil {
-		s.singletonDescriptors = map[reflect.Type]*SingletonDescriptor{}
+	services, err := s.BuildWithOptions(goioc.BuildOptions{})
+	if err != nil {
+		panic(err)
 	}
+	return services
+}
 
-	descriptors := make(map[reflect.Type]goioc.ServiceDescriptor)
-	onces := make(map[reflect.Type]*sync.Once)
+func (s *ServiceCollection) BuildWithOptions(options goioc.BuildOptions) (goioc.IServiceProvider, error) {
+	// 评估条件注册
+	descriptors, order, err := s.resolveDescriptors(&options)
+	if err != nil {
+		return nil, err
+	}
 
-	// 复制集合中的 ServiceDescriptor 到新的容器中，检查
-	for i, descriptor := range s.descriptors {
-		// 单例模式会被放置到全局实例管理器
+	// 检查需要注入的字段，反射无法完成的注入在构建时报错
+	for _, descriptor := range descriptors {
+		if err := checkFields(descriptor.ServiceType); err != nil {
+			return nil, withSource(err, descriptor)
+		}
+	}
+	if err := validateModules(descriptors, order); err != nil {
+		return nil, err
+	}
+	if options.Logger != nil {
+		effective := make([]goioc.ServiceDescriptor, 0, len(order))
+		for _, t := range order {
+			effective = append(effective, descriptors[t])
+		}
+		s.logBuild(options.Logger, effective)
+	}
+
+	// 单例模式会被放置到全局实例管理器
+	for _, descriptor := range descriptors {
 		if descriptor.Lifetime == goioc.Singleton {
-			s.registerSingletonInstance(descriptor.BaseType, descriptor.InitHandler)
+			s.registerSingletonInstance(descriptor, options.FailurePolicy)
 		}
-		descriptors[i] = descriptor
-		onces[i] = &sync.Once{}
 	}
 
-	var services goioc.IServiceProvider
-	services = &ServiceProvider{
-		descriptors:       descriptors,
-		onces:             onces,
-		serviceCollection: s,
+	// 复制集合中的 ServiceDescriptor 到新的容器中
+	services := newServiceProvider(descriptors, options, s, newTracker())
+	if err := validateOnStart(services, descriptors, order); err != nil {
+		return nil, err
 	}
-	return services
+	if options.EagerSingletons {
+		if err := services.Warmup(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	if options.MetricsName != "" {
+		if err := publishMetrics(options.MetricsName, services); err != nil {
+			return nil, err
+		}
+	}
+	return services, nil
+}
+
+// 检查服务是否注入了其它模块的私有服务，返回所有非法的跨模块依赖
+func validateModules(descriptors map[reflect.Type]goioc.ServiceDescriptor, order []reflect.Type) error {
+	var errs []error
+	for _, t := range order {
+		descriptor := descriptors[t]
+		for _, dep := range dependencies(descriptor.ServiceType) {
+			target, ok := descriptors[dep]
+			if !ok || !target.Private || target.Module == descriptor.Module {
+				continue
+			}
+			err := fmt.Errorf("[ %v ] cannot depend on [ %v ], which is private to module [ %s ]", t, dep, target.Module)
+			errs = append(errs, withSource(err, descriptor))
+		}
+	}
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+// 实例化所有实现了 IStartupValidator 的服务并执行校验，返回所有错误
+func validateOnStart(provider *ServiceProvider, descriptors map[reflect.Type]goioc.ServiceDescriptor, order []reflect.Type) error {
+	validatorType := reflect.TypeOf((*goioc.IStartupValidator)(nil)).Elem()
+
+	var errs []error
+	for _, t := range order {
+		descriptor := descriptors[t]
+		if !reflect.PtrTo(descriptor.ServiceType).Implements(validatorType) {
+			continue
+		}
+		obj, err := provider.GetServiceContext(goioc.AsOwner(context.Background()), descriptor.BaseType)
+		if err != nil {
+			errs = append(errs, withSource(err, descriptor))
+			continue
+		}
+		if validator, ok := (*obj).(goioc.IStartupValidator); ok {
+			if err := validator.ValidateOnStart(); err != nil {
+				errs = append(errs, withSource(err, descriptor))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
 }
 
 func (s *ServiceCollection) CopyTo() goioc.IServiceCollection {
@@ -147,31 +338,94 @@ func (s *ServiceCollection) CopyTo() goioc.IServiceCollection {
 		descriptors[i] = descriptor
 	}
 	return &ServiceCollection{
-		descriptors: descriptors,
-		Count:       len(descriptors),
+		descriptors:  descriptors,
+		order:        append([]reflect.Type{}, s.order...),
+		conditionals: append([]goioc.ServiceDescriptor{}, s.conditionals...),
+		overrides:    append([]override{}, s.overrides...),
+		profile:      s.profile,
+		modules:      append([]string{}, s.modules...),
+		Count:        len(descriptors),
+	}
+}
+
+// Descriptors 按注册顺序返回所有生效的 ServiceDescriptor 的副本，
+// 条件注册会按当前环境评估，配置无法实例化时依赖配置的条件不满足
+func (s *ServiceCollection) Descriptors() []goioc.ServiceDescriptor {
+	descriptors, order, _ := s.resolveDescriptors(nil)
+	result := make([]goioc.ServiceDescriptor, 0, len(order))
+	for _, t := range order {
+		result = append(result, descriptors[t])
 	}
+	return result
+}
+
+// 添加一个条件注册
+func (s *ServiceCollection) addConditional(descriptor goioc.ServiceDescriptor) {
+	s.conditionals = append(s.conditionals, descriptor)
+}
+
+// 评估条件注册，返回生效的 ServiceDescriptor 及其注册顺序。
+// 条件注册在普通注册之后按注册顺序评估，满足条件时覆盖同类型的注册；
+// 评估时实例化配置失败会返回错误。
+// options 不为 nil 时表示正在构建，条件中使用的配置会登记到单例管理器，与构建得到的 Provider 共享
+func (s *ServiceCollection) resolveDescriptors(options *goioc.BuildOptions) (map[reflect.Type]goioc.ServiceDescriptor, []reflect.Type, error) {
+	descriptors := make(map[reflect.Type]goioc.ServiceDescriptor, len(s.descriptors))
+	for t, descriptor := range s.descriptors {
+		descriptors[t] = descriptor
+	}
+	order := append([]reflect.Type{}, s.order...)
+
+	ctx := &conditionContext{collection: s, descriptors: descriptors, pending: map[reflect.Type]int{}}
+	switch {
+	case options != nil:
+		if s.singletonDescriptors == nil {
+			s.singletonDescriptors = map[reflect.Type]*SingletonDescriptor{}
+		}
+		ctx.singletons = s
+		ctx.policy = options.FailurePolicy
+	case s.singletonDescriptors != nil:
+		ctx.singletons = s
+	default:
+		// 还没有构建过，不修改单例管理器
+		ctx.singletons = &ServiceCollection{singletonDescriptors: map[reflect.Type]*SingletonDescriptor{}}
+	}
+	for _, descriptor := range s.conditionals {
+		ctx.pending[descriptor.BaseType]++
+	}
+	for _, descriptor := range s.conditionals {
+		if !descriptor.Condition(ctx) {
+			ctx.evaluated(descriptor.BaseType, false)
+			continue
+		}
+		if _, ok := descriptors[descriptor.BaseType]; !ok {
+			order = append(order, descriptor.BaseType)
+		}
+		descriptors[descriptor.BaseType] = descriptor
+		ctx.evaluated(descriptor.BaseType, true)
+	}
+	return descriptors, order, ctx.err
 }
 
 // 静态对象处理
 // 注册静态实例
-func (s *ServiceCollection) registerSingletonInstance(baseType reflect.Type, f func(provider goioc.IServiceProvider) interface{}) {
+func (s *ServiceCollection) registerSingletonInstance(serviceDescriptor goioc.ServiceDescriptor, policy goioc.FailurePolicy) {
+	baseType := serviceDescriptor.BaseType
 	if s.singletonDescriptors[baseType] != nil {
 		return
 	}
-	once := &sync.Once{}
 	descriptor := SingletonDescriptor{
 		baseType:    baseType,
-		initHandler: f,
-		lock:        once,
+		initHandler: serviceDescriptor.InitHandler,
+		policy:      policy,
+		lock:        &sync.Mutex{},
 	}
 	s.singletonDescriptors[baseType] = &descriptor
 }
 
-func (s *ServiceCollection) getSingletonInstance(baseType reflect.Type, provider *ServiceProvider) interface{} {
-	descriptor := s.singletonDescriptors[baseType]
+func (s *ServiceCollection) getSingletonInstance(r resolveContext, event goioc.ResolveEvent, provider *ServiceProvider) (interface{}, error) {
+	descriptor := s.singletonDescriptors[event.Type]
 	if descriptor == nil {
-		return nil
+		return nil, nil
 	}
-	obj := descriptor.initAndGet(provider)
-	return obj
+	return descriptor.initAndGet(r, event, provider)
 }
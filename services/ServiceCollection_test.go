@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"github.com/whuanle/goioc"
+	"reflect"
 	"testing"
 )
 
@@ -18,3 +20,249 @@ func TestCreateCollection(t *testing.T) {
 		t.Errorf("service is nil!")
 	}
 }
+
+// 不同环境使用不同的实现
+type IStore interface {
+	Name() string
+}
+
+type MemoryStore struct{}
+
+func (my *MemoryStore) Name() string { return "memory" }
+
+type RedisStore struct{}
+
+func (my *RedisStore) Name() string { return "redis" }
+
+type FileStore struct{}
+
+func (my *FileStore) Name() string { return "file" }
+
+func TestConditionalProfile(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IStore, FileStore](sc, goioc.Transient)
+	goioc.AddServiceOf[IStore, MemoryStore](sc.When(goioc.OnProfile(goioc.Development)), goioc.Transient)
+	goioc.AddServiceOf[IStore, RedisStore](sc.When(goioc.OnProfile(goioc.Production, "staging")), goioc.Transient)
+
+	expected := map[string]string{
+		goioc.Development: "memory",
+		goioc.Production:  "redis",
+		"Staging":         "redis",
+		"Test":            "file",
+	}
+	for profile, name := range expected {
+		sc.SetProfile(profile)
+		p := sc.Build()
+		if store := goioc.GetI[IStore](p); store.Name() != name {
+			t.Errorf("profile %s: store = %s, expected %s", profile, store.Name(), name)
+		}
+	}
+}
+
+func TestConditionalConfigAndPresence(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{"Cache:Type": "Redis"}))
+	goioc.AddServiceOf[IStore, RedisStore](sc.When(goioc.OnConfig("Cache:Type", "redis")), goioc.Transient)
+	goioc.AddServiceOf[IStore, MemoryStore](sc.When(goioc.OnMissing[IStore]()), goioc.Transient)
+	goioc.AddService[Dog](sc.When(goioc.OnPresent[IStore]()).When(goioc.OnProfile(goioc.Development)), goioc.Transient)
+
+	p := sc.Build()
+	if store := goioc.GetI[IStore](p); store.Name() != "redis" {
+		t.Errorf("store = %s, expected redis", store.Name())
+	}
+	if _, err := p.GetService(reflect.TypeOf((*Dog)(nil)).Elem()); err == nil {
+		t.Errorf("combined condition should not be satisfied")
+	}
+
+	sc.SetProfile(goioc.Development)
+	if len(sc.Descriptors()) != 3 {
+		t.Errorf("descriptors = %d, expected 3", len(sc.Descriptors()))
+	}
+}
+
+// 配置依赖的单例
+type Secrets struct {
+	Cache string
+}
+
+func TestConditionalConfigOnce(t *testing.T) {
+	sc := &ServiceCollection{}
+	calls := 0
+	goioc.AddServiceHandler[Secrets](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		return &Secrets{Cache: "redis"}
+	})
+	goioc.AddServiceHandlerOf[goioc.IConfiguration, goioc.IConfiguration](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		calls++
+		secrets := goioc.GetS[Secrets](provider)
+		return newMapConfiguration(map[string]string{"Cache:Type": secrets.Cache})
+	})
+	goioc.AddServiceOf[IStore, RedisStore](sc.When(goioc.OnConfig("Cache:Type", "redis")), goioc.Transient)
+	goioc.AddService[Dog](sc.When(goioc.OnConfig("Cache:Type", "")), goioc.Transient)
+
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store := goioc.GetI[IStore](p); store.Name() != "redis" {
+		t.Errorf("store = %s, expected redis", store.Name())
+	}
+	// 条件中使用的配置与 Provider 共享同一个实例
+	goioc.GetI[goioc.IConfiguration](p)
+	sc.Descriptors()
+	if calls != 1 {
+		t.Errorf("configuration is created %d times, expected 1", calls)
+	}
+
+	failed := errors.New("configuration failed")
+	sc = &ServiceCollection{}
+	goioc.AddServiceHandlerOf[goioc.IConfiguration, goioc.IConfiguration](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		panic(failed)
+	})
+	goioc.AddServiceOf[IStore, RedisStore](sc.When(goioc.OnConfig("Cache:Type", "redis")), goioc.Transient)
+	if _, err := sc.Describe(); !errors.Is(err, failed) {
+		t.Errorf("configuration error is not reported by Describe, got %v", err)
+	}
+	if _, err := sc.BuildWithOptions(goioc.BuildOptions{}); !errors.Is(err, failed) {
+		t.Errorf("configuration error is not reported, got %v", err)
+	}
+}
+
+// 之后的条件注册可能覆盖的单例不会被提前登记
+func TestConditionalConfigOverride(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddConfiguration(sc, newMapConfiguration(map[string]string{"Cache:Type": "redis"}))
+	goioc.AddServiceOf[IStore, FileStore](sc, goioc.Singleton)
+	goioc.AddServiceOf[IStore, RedisStore](sc.When(goioc.OnConfig("Cache:Type", "redis")), goioc.Singleton)
+
+	p := sc.Build()
+	if store := goioc.GetI[IStore](p); store.Name() != "redis" {
+		t.Errorf("store = %s, expected redis", store.Name())
+	}
+}
+
+// 测试用的模块
+type testModule struct {
+	name      string
+	deps      []goioc.IModule
+	configure func(sc goioc.IServiceCollection)
+	count     *int
+}
+
+func (m *testModule) Name() string { return m.name }
+
+func (m *testModule) DependsOn() []goioc.IModule { return m.deps }
+
+func (m *testModule) ConfigureServices(sc goioc.IServiceCollection) {
+	*m.count++
+	if m.configure != nil {
+		m.configure(sc)
+	}
+}
+
+func TestAddModule(t *testing.T) {
+	var order []string
+	count := 0
+	newModule := func(name string, configure func(sc goioc.IServiceCollection), deps ...goioc.IModule) *testModule {
+		return &testModule{name: name, deps: deps, count: &count, configure: func(sc goioc.IServiceCollection) {
+			order = append(order, name)
+			if configure != nil {
+				configure(sc)
+			}
+		}}
+	}
+
+	storage := newModule("storage", func(sc goioc.IServiceCollection) {
+		goioc.AddServiceOf[IStore, MemoryStore](sc, goioc.Scope)
+	})
+	animal := newModule("animal", func(sc goioc.IServiceCollection) {
+		goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	}, storage)
+	app := newModule("app", nil, animal, storage)
+
+	sc := &ServiceCollection{}
+	sc.AddModule(app)
+	sc.AddModule(storage)
+
+	if count != 3 || len(order) != 3 || order[0] != "storage" || order[1] != "animal" || order[2] != "app" {
+		t.Errorf("modules are not ordered by dependency: %v", order)
+	}
+	for _, descriptor := range sc.Descriptors() {
+		if descriptor.BaseType.Name() == "IStore" && descriptor.Module != "storage" {
+			t.Errorf("module of IStore = %s", descriptor.Module)
+		}
+		if descriptor.BaseType.Name() == "IAnimal" && descriptor.Module != "animal" {
+			t.Errorf("module of IAnimal = %s", descriptor.Module)
+		}
+	}
+}
+
+func TestAddModuleCycle(t *testing.T) {
+	count := 0
+	a := &testModule{name: "a", count: &count}
+	b := &testModule{name: "b", count: &count, deps: []goioc.IModule{a}}
+	a.deps = []goioc.IModule{b}
+
+	defer func() {
+		if err := recover(); err == nil {
+			t.Errorf("module cycle is not detected")
+		}
+	}()
+	sc := &ServiceCollection{}
+	sc.AddModule(a)
+}
+
+// 显式导出服务的模块
+type exportingModule struct {
+	testModule
+	exports []reflect.Type
+}
+
+func (m *exportingModule) Exports() []reflect.Type { return m.exports }
+
+func TestModuleExports(t *testing.T) {
+	count := 0
+	zoo := &exportingModule{
+		testModule: testModule{name: "zoo", count: &count, configure: func(sc goioc.IServiceCollection) {
+			goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+			goioc.AddService[Animal](sc, goioc.Transient)
+			goioc.AddServiceHandlerOf[IStore, MemoryStore](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+				// 模块中的工厂函数可以获取模块私有的服务
+				if _, err := provider.GetService(reflect.TypeOf((*IAnimal)(nil)).Elem()); err != nil {
+					panic(err)
+				}
+				return &MemoryStore{}
+			})
+		}},
+		exports: []reflect.Type{reflect.TypeOf(Animal{}), reflect.TypeOf((*IStore)(nil)).Elem()},
+	}
+
+	sc := &ServiceCollection{}
+	sc.AddModule(zoo)
+	p := sc.Build()
+
+	if _, err := p.GetService(reflect.TypeOf((*IAnimal)(nil)).Elem()); err == nil {
+		t.Errorf("private service should not be resolvable outside the module")
+	}
+	if animal := goioc.GetS[Animal](p); animal.Dog == nil {
+		t.Errorf("private service is not injected into the same module")
+	}
+	if store := goioc.GetI[IStore](p); store.Name() != "memory" {
+		t.Errorf("store = %s, expected memory", store.Name())
+	}
+}
+
+func TestModuleIllegalDependency(t *testing.T) {
+	count := 0
+	zoo := &exportingModule{
+		testModule: testModule{name: "zoo", count: &count, configure: func(sc goioc.IServiceCollection) {
+			goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+		}},
+	}
+
+	sc := &ServiceCollection{}
+	sc.AddModule(zoo)
+	goioc.AddService[Animal](sc, goioc.Transient)
+	if _, err := sc.BuildWithOptions(goioc.BuildOptions{}); err == nil {
+		t.Errorf("cross-module dependency on a private service should fail to build")
+	}
+}
@@ -1,163 +1,480 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"github.com/whuanle/goioc"
+	"log/slog"
 	"reflect"
 	"sync"
+	"time"
 )
 
 type ServiceProvider struct {
-	descriptors       map[reflect.Type]goioc.ServiceDescriptor
-	onces             map[reflect.Type]*sync.Once
+	descriptors map[reflect.Type]goioc.ServiceDescriptor
+	locks       map[reflect.Type]*sync.Mutex
+	// 策略为 CacheFailure 时缓存的 Scope 对象实例化错误
+	errors            map[reflect.Type]error
+	options           goioc.BuildOptions
 	serviceCollection *ServiceCollection
+	// 与同一次 Build 得到的所有作用域共享的运行状态
+	tracker *tracker
+	// 作用域编号，通过 Build 得到的 Provider 为 0
+	scope int64
+	// 保护 descriptors 中的 ScopeInstance 以及 errors
+	lock sync.RWMutex
 }
 
-// Dispose 释放所有对象
+// 创建 ServiceProvider，复制 ServiceDescriptor 并清空其中的 Scope 对象
+func newServiceProvider(descriptors map[reflect.Type]goioc.ServiceDescriptor, options goioc.BuildOptions, collection *ServiceCollection, tracker *tracker) *ServiceProvider {
+	s := &ServiceProvider{
+		descriptors:       make(map[reflect.Type]goioc.ServiceDescriptor, len(descriptors)),
+		locks:             make(map[reflect.Type]*sync.Mutex, len(descriptors)),
+		errors:            map[reflect.Type]error{},
+		options:           options,
+		serviceCollection: collection,
+		tracker:           tracker,
+	}
+	for i, descriptor := range descriptors {
+		descriptor.ScopeInstance = nil
+		s.descriptors[i] = descriptor
+		s.locks[i] = &sync.Mutex{}
+	}
+	return s
+}
+
+// CreateScope 创建一个新的作用域，
+// Singleton 对象与当前 Provider 共享，Scope 对象在新的作用域中重新实例化
+func (s *ServiceProvider) CreateScope() goioc.IServiceProvider {
+	s.lock.RLock()
+	scope := newServiceProvider(s.descriptors, s.options, s.serviceCollection, s.tracker)
+	s.lock.RUnlock()
+	scope.scope = s.tracker.addScope()
+	if hook := s.options.Hooks.OnScopeCreated; hook != nil {
+		hook(scope)
+	}
+	return scope
+}
+
+// Dispose 释放所有对象，
+// 实例的 Dispose 接口和 Hooks 在释放锁之后调用，Dispose 接口 panic 时记录日志并继续释放其它实例
 func (s *ServiceProvider) Dispose() {
+	var disposed []goioc.ServiceDescriptor
+	s.lock.Lock()
 	for i, _ := range s.descriptors {
 		instance := s.descriptors[i]
 		if instance.ScopeInstance != nil {
-			if obj, ok := instance.ScopeInstance.(goioc.IDispose); ok {
-				obj.Dispose()
+			if _, ok := instance.ScopeInstance.(goioc.IDispose); ok {
+				disposed = append(disposed, instance)
 			}
 			instance.ScopeInstance = nil
 		}
 
 		s.descriptors[i] = instance
 	}
+	s.lock.Unlock()
+
+	for _, descriptor := range disposed {
+		if err := dispose(descriptor.ScopeInstance.(goioc.IDispose)); err != nil {
+			if logger := s.options.Logger; logger != nil {
+				attrs := serviceAttrs(descriptor.BaseType, fmt.Sprintf("%T", descriptor.ScopeInstance), descriptor.Lifetime)
+				logger.Error("dispose failed", append(attrs, slog.Any("error", err))...)
+			}
+			continue
+		}
+		s.tracker.dispose()
+		if hook := s.options.Hooks.OnDisposed; hook != nil {
+			hook(descriptor.BaseType, descriptor.ScopeInstance)
+		}
+	}
+	if s.scope != 0 {
+		s.tracker.removeScope(s.scope)
+		if hook := s.options.Hooks.OnScopeDisposed; hook != nil {
+			hook(s)
+		}
+	}
+}
+
+// SetScopeInstance 将 instance 作为 baseType 在当前作用域中的 Scope 对象，
+// 用于注入只有运行时才能得到的值，如 HTTP 请求。
+// baseType 没有注册时会在当前作用域中注册为 Scope，已经注册为其它生命周期时返回错误
+func (s *ServiceProvider) SetScopeInstance(baseType reflect.Type, instance interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	descriptor, ok := s.descriptors[baseType]
+	if !ok {
+		descriptor = newDescriptor(goioc.Scope, baseType, baseType, func(provider goioc.IServiceProvider) interface{} {
+			panic(fmt.Sprintf("[ %v ] is only available in the scope where it is set", baseType))
+		})
+		s.locks[baseType] = &sync.Mutex{}
+	}
+	if descriptor.Lifetime != goioc.Scope {
+		return fmt.Errorf("cannot set [ %v ] whose lifecycle is %v as a scope instance", baseType, descriptor.Lifetime)
+	}
+	descriptor.ScopeInstance = instance
+	s.descriptors[baseType] = descriptor
+	delete(s.errors, baseType)
+	return nil
+}
+
+// 执行 Dispose 接口，Dispose panic 时转换为错误，不影响其它实例的释放
+func dispose(obj goioc.IDispose) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("dispose panicked: %v", r)
+		}
+	}()
+	obj.Dispose()
+	return nil
 }
 
 // GetService 获取对象实例
 func (s *ServiceProvider) GetService(baseType reflect.Type) (*interface{}, error) {
+	return s.GetServiceContext(context.Background(), baseType)
+}
+
+// GetServiceContext 获取对象实例，ctx 会传递给 IInitializeContext
+func (s *ServiceProvider) GetServiceContext(ctx context.Context, baseType reflect.Type) (*interface{}, error) {
 	defer func() {
 		if err := recover(); err != nil {
-			panic(fmt.Errorf("error instantiating the object: [ v% ]", err))
+			panic(fmt.Errorf("error instantiating the object: [ %v ]", err))
 		}
 	}()
+	return getService(s, resolveContext{ctx: ctx, lifetime: goioc.Transient}, baseType)
+}
+
+// resolveContext 实例化对象时传递的上下文
+type resolveContext struct {
+	ctx context.Context
+	// 被注入的对象的生命周期
+	lifetime goioc.ServiceLifetime
+	// 被注入的对象所属模块，从容器外部获取对象时为空
+	module string
+	// 被注入的对象类型及其解析深度，从容器外部获取对象时为 nil 和 0
+	parent reflect.Type
+	depth  int
+}
+
+// 实例化 descriptor 对应的对象时使用的上下文，即其字段的上下文，
+// Singleton 对象依赖的 Transient 对象同样被 Singleton 持有，其字段按 Singleton 检查
+func (r resolveContext) child(descriptor goioc.ServiceDescriptor) resolveContext {
+	lifetime := descriptor.Lifetime
+	if lifetime == goioc.Transient && r.lifetime == goioc.Singleton {
+		lifetime = goioc.Singleton
+	}
+	return resolveContext{
+		ctx:      r.ctx,
+		lifetime: lifetime,
+		module:   descriptor.Module,
+		parent:   descriptor.BaseType,
+		depth:    r.depth + 1,
+	}
+}
+
+// 获取对象，并检测生命周期和模块可见性。
+func getService(s *ServiceProvider, r resolveContext, baseType reflect.Type) (*interface{}, error) {
+	s.lock.RLock()
 	descriptor, ok := s.descriptors[baseType]
-	if !ok {
-		return nil, fmt.Errorf("type [ %t ] not found", baseType)
+	s.lock.RUnlock()
+	// 框架内部以服务所属模块的身份获取对象
+	if r.depth == 0 && goioc.IsOwner(r.ctx) {
+		r.module = descriptor.Module
+	}
+
+	event := goioc.ResolveEvent{Type: baseType, Lifetime: descriptor.Lifetime, Depth: r.depth, Parent: r.parent}
+	s.tracker.resolving(baseType)
+	if hook := s.options.Hooks.OnResolving; hook != nil {
+		hook(event)
+	}
+	var instance interface{}
+	var err error
+	if ok {
+		instance, err = resolve(s, r, event, descriptor)
+	} else {
+		err = fmt.Errorf("type [ %v ] not found", baseType)
+	}
+	if err != nil {
+		s.tracker.failed(baseType)
+		if hook := s.options.Hooks.OnResolveFailed; hook != nil {
+			hook(event, err)
+		}
+		return nil, err
+	}
+	return &instance, nil
+}
+
+// 按生命周期获取 descriptor 对应的对象
+func resolve(s *ServiceProvider, r resolveContext, event goioc.ResolveEvent, descriptor goioc.ServiceDescriptor) (interface{}, error) {
+	baseType := descriptor.BaseType
+	if descriptor.Private && descriptor.Module != r.module {
+		return nil, fmt.Errorf("type [ %v ] is private to module [ %s ]", baseType, descriptor.Module)
 	}
 	if descriptor.Lifetime == goioc.Transient {
-		obj := descriptor.InitHandler(s)
 		// 创建对象并且检查当前结构体是否还有需要被注入的字段
-		obj = createObject(s, obj, goioc.Transient)
-		return &obj, nil
+		return newInstance(s, r.child(descriptor), event, descriptor.InitHandler)
 	}
 
 	// descriptor.Lifetime == Scope
 	if descriptor.Lifetime == goioc.Scope {
-		if descriptor.ScopeInstance == nil {
-			once := s.onces[descriptor.BaseType]
-			once.Do(func() {
-				obj := descriptor.InitHandler(s)
-				descriptor.ScopeInstance = createObject(s, obj, goioc.Scope)
-				s.descriptors[baseType] = descriptor
-			})
+		if r.lifetime == goioc.Singleton {
+			return nil, fmt.Errorf("cannot inject an instance whose lifecycle is scope [ %v ] into singleton", baseType)
 		}
-		return &descriptor.ScopeInstance, nil
+		return s.getScopeInstance(r.child(descriptor), event, descriptor)
 	}
 
 	// 如果是单例模式，则要找到原始的 collection ，实例化，每次都从 ServiceCollection 中取对象
 	if descriptor.Lifetime == goioc.Singleton {
-		instance := s.serviceCollection.getSingletonInstance(baseType, s)
+		instance, err := s.serviceCollection.getSingletonInstance(r.child(descriptor), event, s)
+		if err != nil {
+			return nil, err
+		}
 		if instance == nil {
-			return nil, fmt.Errorf("type [ %t ] not found", baseType)
+			return nil, fmt.Errorf("type [ %v ] not found", baseType)
 		}
-		return &instance, nil
+		return instance, nil
 	}
 	panic(fmt.Sprintf("Unrecognized life cycle: [ %v ]", descriptor.Lifetime))
 }
 
-// 获取对象，并检测生命周期。
-// sourceLifetime：被注入的对象的生命周期
-func getService(s *ServiceProvider, baseType reflect.Type, sourceLifetime goioc.ServiceLifetime) (*interface{}, error) {
-	descriptor, ok := s.descriptors[baseType]
-	if !ok {
-		return nil, fmt.Errorf("type [ %t ] not found", baseType)
+// 获取 Scope 对象，同一个 Provider 中只会实例化一次，
+// 实例化失败时根据 FailurePolicy 决定重新实例化还是返回缓存的错误
+func (s *ServiceProvider) getScopeInstance(r resolveContext, event goioc.ResolveEvent, descriptor goioc.ServiceDescriptor) (interface{}, error) {
+	s.lock.RLock()
+	lock := s.locks[descriptor.BaseType]
+	s.lock.RUnlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.lock.RLock()
+	instance := s.descriptors[descriptor.BaseType].ScopeInstance
+	err := s.errors[descriptor.BaseType]
+	s.lock.RUnlock()
+	if instance != nil {
+		return instance, nil
 	}
-	if descriptor.Lifetime == goioc.Transient {
-		obj := descriptor.InitHandler(s)
-		// 创建对象并且检查当前结构体是否还有需要被注入的字段
-		obj = createObject(s, obj, descriptor.Lifetime)
-		return &obj, nil
+	if err != nil {
+		return nil, err
 	}
 
-	// descriptor.Lifetime == Scope
-	if descriptor.Lifetime == goioc.Scope {
-		if sourceLifetime == goioc.Singleton {
-			panic(fmt.Sprintf("Cannot inject an instance whose lifecycle is scope[ %t ] into singleton", baseType))
+	instance, err = newInstance(s, r, event, descriptor.InitHandler)
+	if err != nil {
+		if s.options.FailurePolicy == goioc.CacheFailure {
+			s.lock.Lock()
+			s.errors[descriptor.BaseType] = err
+			s.lock.Unlock()
 		}
-		if descriptor.ScopeInstance == nil {
-			once := s.onces[descriptor.BaseType]
-			once.Do(func() {
-				obj := descriptor.InitHandler(s)
-				s.descriptors[baseType] = descriptor
-				descriptor.ScopeInstance = createObject(s, obj, descriptor.Lifetime)
-			})
+		return nil, err
+	}
+
+	s.lock.Lock()
+	descriptor = s.descriptors[descriptor.BaseType]
+	descriptor.ScopeInstance = instance
+	s.descriptors[descriptor.BaseType] = descriptor
+	s.lock.Unlock()
+	return instance, nil
+}
 
+// newInstance 实例化对象，注入字段后执行初始化接口，并记录实例化耗时。
+// r 是对象字段的上下文，event 是对象本身的事件信息
+func newInstance(s *ServiceProvider, r resolveContext, event goioc.ResolveEvent, f func(provider goioc.IServiceProvider) interface{}) (interface{}, error) {
+	start := time.Now()
+	var obj interface{}
+	var err error
+	instrument(r.ctx, event.Type, s.options.ProfilerLabels, func(ctx context.Context) {
+		r.ctx = ctx
+		obj, err = construct(s, r, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(start)
+	s.tracker.created(event.Type, start, duration)
+	if hook := s.options.Hooks.OnCreated; hook != nil {
+		hook(event, obj, duration)
+	}
+	if logger := s.options.Logger; logger != nil {
+		attrs := append(serviceAttrs(event.Type, fmt.Sprintf("%T", obj), event.Lifetime), slog.Duration("duration", duration))
+		if threshold := s.options.SlowFactoryThreshold; threshold > 0 && duration >= threshold {
+			logger.Warn("slow factory", attrs...)
+		}
+		if event.Lifetime == goioc.Singleton {
+			logger.Debug("singleton created", attrs...)
 		}
-		return &descriptor.ScopeInstance, nil
 	}
+	return obj, nil
+}
 
-	// 如果是单例模式，则要找到原始的 collection ，实例化，每次都从 ServiceCollection 中取对象
-	if descriptor.Lifetime == goioc.Singleton {
-		instance := s.serviceCollection.getSingletonInstance(baseType, s)
-		if instance == nil {
-			return nil, fmt.Errorf("type [ %t ] not found", baseType)
+// 执行工厂函数、注入字段并执行初始化接口
+func construct(s *ServiceProvider, r resolveContext, f func(provider goioc.IServiceProvider) interface{}) (interface{}, error) {
+	obj, err := invokeHandler(&handlerProvider{ServiceProvider: s, parent: r}, f)
+	if err != nil {
+		return nil, err
+	}
+	obj, err = createObject(s, r, obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := initialize(r.ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// 执行工厂函数，工厂函数 panic 时转换为错误，
+// 避免 panic 穿过正在持有的锁；panic 的值是 error 时保留原始错误，可以通过 errors.As 获取
+func invokeHandler(provider goioc.IServiceProvider, f func(provider goioc.IServiceProvider) interface{}) (obj interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = fmt.Errorf("factory failed: %w", e)
+				return
+			}
+			err = fmt.Errorf("factory panicked: %v", r)
+		}
+	}()
+	return f(provider), nil
+}
+
+// handlerProvider 传递给工厂函数的 Provider，
+// 工厂函数中获取的对象与字段注入一样属于正在实例化的对象，可以获取同一个模块的私有服务
+type handlerProvider struct {
+	*ServiceProvider
+	// 正在实例化的对象的字段上下文
+	parent resolveContext
+}
+
+func (h *handlerProvider) GetService(baseType reflect.Type) (*interface{}, error) {
+	return h.GetServiceContext(context.Background(), baseType)
+}
+
+func (h *handlerProvider) GetServiceContext(ctx context.Context, baseType reflect.Type) (*interface{}, error) {
+	r := h.parent
+	r.ctx = ctx
+	// 工厂函数自行决定如何使用获取的对象，不检查生命周期
+	r.lifetime = goioc.Transient
+	return getService(h.ServiceProvider, r, baseType)
+}
+
+// 执行 IInitialize、IInitializeContext 初始化接口
+func initialize(ctx context.Context, obj interface{}) error {
+	if o, ok := obj.(goioc.IInitialize); ok {
+		if err := o.Init(); err != nil {
+			return fmt.Errorf("initialize [ %T ]: %w", obj, err)
 		}
-		return &instance, nil
 	}
-	panic(fmt.Sprintf("Unrecognized life cycle: [ %v ]", descriptor.Lifetime))
+	if o, ok := obj.(goioc.IInitializeContext); ok {
+		if err := o.InitContext(ctx); err != nil {
+			return fmt.Errorf("initialize [ %T ]: %w", obj, err)
+		}
+	}
+	return nil
 }
 
 // createObject 结构体字段自动注入，
 // 递归给需要依赖注入的结构体字段注入实例。
 // obj 对应的结构体需要是结构体指针，
 // 创建对象后必须返回结构体指针；
-func createObject(s *ServiceProvider, obj interface{}, lifetime goioc.ServiceLifetime) interface{} {
-	sourceType := reflect.TypeOf(obj).Elem()
-	if sourceType.Kind() != reflect.Struct {
-		panic(fmt.Sprintf("[ %t ] is not an interface or struct", sourceType))
+func createObject(s *ServiceProvider, r resolveContext, obj interface{}) (interface{}, error) {
+	sourceType := reflect.TypeOf(obj)
+	if sourceType == nil || sourceType.Kind() != reflect.Ptr || sourceType.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("[ %v ] is not a struct pointer", sourceType)
 	}
 
 	// 如果是 interface = Type{} 则不需要处理，否则解开 interface = &Type{} ，获取 Type；
 	//必须使用 &obj 而不是 obj，否则无法通过方式为其赋值， reflect.TypeOf(&obj).Elem()
 	v := reflect.ValueOf(obj).Elem() // 获取执行的对象
+	if err := injectFields(s, r, v, v.Type().Name()); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// injectFields 给结构体 v 中需要被依赖注入的字段赋值，
+// 匿名嵌入的结构体和结构体指针会被递归处理，path 是错误信息中 v 的路径
+func injectFields(s *ServiceProvider, r resolveContext, v reflect.Value, path string) error {
 	t := v.Type()
 
 	// 找到需要被依赖注入的字段
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		tag := field.Tag.Get("ioc")
-		if tag == "" {
+		fieldPath := path + "." + field.Name
+		tag := parseTag(field)
+		if tag.config != "" {
+			if err := injectConfig(s, r, v.Field(i), field, tag); err != nil {
+				return fmt.Errorf("field [ %s ]: %w", fieldPath, err)
+			}
 			continue
 		}
-		if tag == "true" {
-			// 字段类型，如果字段类型是指针，则需要解开指针
-			fieldSourceType := field.Type
-			if fieldSourceType.Kind() == reflect.Ptr {
-				fieldSourceType = fieldSourceType.Elem()
-			}
-			value, err := getService(s, fieldSourceType, lifetime)
-			if err != nil {
-				panic(err)
+		if !tag.inject {
+			if field.Anonymous {
+				if err := injectEmbedded(s, r, v.Field(i), field, fieldPath); err != nil {
+					return err
+				}
 			}
+			continue
+		}
+		if !field.IsExported() && !tag.unexported {
+			return fmt.Errorf("field [ %s ] is unexported, use `ioc:\"true,unexported\"` to allow injection", fieldPath)
+		}
 
-			// 赋值
-			rValue := reflect.ValueOf(*value)
-			switch field.Type.Kind() {
-			case reflect.Interface:
-				break
-			case reflect.Ptr:
-				break
-			case reflect.Struct:
-				rValue = rValue.Elem()
-			}
+		// 字段类型，如果字段类型是指针，则需要解开指针
+		fieldSourceType := field.Type
+		if fieldSourceType.Kind() == reflect.Ptr {
+			fieldSourceType = fieldSourceType.Elem()
+		}
+		value, err := getService(s, r, fieldSourceType)
+		if err != nil {
+			return err
+		}
+
+		// 赋值
+		rValue := reflect.ValueOf(*value)
+		switch field.Type.Kind() {
+		case reflect.Interface:
+			break
+		case reflect.Ptr:
+			break
+		case reflect.Struct:
+			rValue = rValue.Elem()
+		}
+
+		settable(v.Field(i)).Set(rValue)
+	}
+	return nil
+}
 
-			v.Field(i).Set(rValue)
+// injectConfig 从容器中的 IConfiguration 读取 tag 中的键，转换为字段类型后赋值，
+// 配置中不存在时使用 `default:"..."` tag 的值
+func injectConfig(s *ServiceProvider, r resolveContext, fv reflect.Value, field reflect.StructField, tag iocTag) error {
+	if !field.IsExported() && !tag.unexported {
+		return fmt.Errorf("field is unexported, use `ioc:\"config=%s,unexported\"` to allow injection", tag.config)
+	}
+	value, err := getService(s, r, configurationType)
+	if err != nil {
+		return err
+	}
+	configuration := (*value).(goioc.IConfiguration)
+	return goioc.BindField(configuration, tag.config, settable(fv), field.Tag)
+}
+
+// injectEmbedded 处理匿名嵌入的结构体或结构体指针，
+// 只有其中包含需要注入的字段时才会处理，嵌入的指针为 nil 时会自动创建实例，
+// path 是错误信息中该字段的路径
+func injectEmbedded(s *ServiceProvider, r resolveContext, fv reflect.Value, field reflect.StructField, path string) error {
+	if !hasInjectFields(field.Type, map[reflect.Type]bool{}) {
+		return nil
+	}
+	switch field.Type.Kind() {
+	case reflect.Struct:
+		return injectFields(s, r, fv, path)
+	case reflect.Ptr:
+		if fv.IsNil() {
+			if !fv.CanSet() {
+				return fmt.Errorf("embedded field [ %s ] is an unexported pointer and cannot be allocated", path)
+			}
+			fv.Set(reflect.New(field.Type.Elem()))
 		}
+		return injectFields(s, r, fv.Elem(), path)
 	}
-	return obj
+	return nil
 }
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/whuanle/goioc"
 	"reflect"
+	"runtime/pprof"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // 定义接口和结构体
@@ -311,3 +317,490 @@ func TestFieldType(t *testing.T) {
 	fmt.Println(*v2)
 	fmt.Println(*v3)
 }
+
+// 未导出字段
+type Animal4 struct {
+	dog IAnimal `ioc:"true,unexported"`
+}
+
+// 未声明 unexported 的未导出字段
+type Animal5 struct {
+	dog IAnimal `ioc:"true"`
+}
+
+// 嵌入的结构体
+type Animal6 struct {
+	Animal
+}
+
+// 嵌入的结构体指针
+type Animal7 struct {
+	*Animal4
+}
+
+// 未导出的嵌入结构体指针
+type animal8 struct {
+	Dog IAnimal `ioc:"true"`
+}
+
+type Animal8 struct {
+	*animal8
+}
+
+func TestInjectUnexportedField(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Scope, func(provider goioc.IServiceProvider) interface{} {
+		return &Dog{
+			Id: 666,
+		}
+	})
+	goioc.AddService[Animal4](sc, goioc.Scope)
+
+	p := sc.Build()
+	a := goioc.GetS[Animal4](p)
+	if dog := a.dog.(*Dog); dog.Id != 666 {
+		t.Errorf("unexported field is not injected!")
+	}
+}
+
+func TestInjectEmbeddedField(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Scope, func(provider goioc.IServiceProvider) interface{} {
+		return &Dog{
+			Id: 666,
+		}
+	})
+	goioc.AddService[Animal6](sc, goioc.Scope)
+	goioc.AddService[Animal7](sc, goioc.Scope)
+
+	p := sc.Build()
+	a6 := goioc.GetS[Animal6](p)
+	if a6.Dog == nil || a6.Dog.(*Dog).Id != 666 {
+		t.Errorf("embedded struct is not injected!")
+	}
+	a7 := goioc.GetS[Animal7](p)
+	if a7.Animal4 == nil || a7.dog.(*Dog).Id != 666 {
+		t.Errorf("embedded struct pointer is not injected!")
+	}
+}
+
+func TestInjectFieldBuildError(t *testing.T) {
+	build := func(f func(sc *ServiceCollection)) (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		sc := &ServiceCollection{}
+		goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+		f(sc)
+		sc.Build()
+		return nil
+	}
+
+	if err := build(func(sc *ServiceCollection) { goioc.AddService[Animal5](sc, goioc.Scope) }); err == nil {
+		t.Errorf("unexported field without opt-in should fail to build")
+	}
+	if err := build(func(sc *ServiceCollection) { goioc.AddService[Animal8](sc, goioc.Scope) }); err == nil {
+		t.Errorf("unexported embedded pointer should fail to build")
+	}
+}
+
+// 初始化接口
+type Cat struct {
+	Dog   IAnimal `ioc:"true"`
+	Ready bool
+	Fail  bool
+	Count int
+}
+
+func (my *Cat) Init() error {
+	my.Count++
+	if my.Dog == nil {
+		return fmt.Errorf("dog is not injected")
+	}
+	if my.Fail {
+		return fmt.Errorf("init failed")
+	}
+	my.Ready = true
+	return nil
+}
+
+type ctxKey struct{}
+
+type Bird struct {
+	Name string
+}
+
+func (my *Bird) InitContext(ctx context.Context) error {
+	my.Name, _ = ctx.Value(ctxKey{}).(string)
+	return nil
+}
+
+func TestInitialize(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Cat](sc, goioc.Transient)
+	goioc.AddService[Bird](sc, goioc.Singleton)
+	p := sc.Build()
+
+	cat := goioc.GetS[Cat](p)
+	if !cat.Ready || cat.Count != 1 {
+		t.Errorf("Init is not called after injection")
+	}
+
+	birdType := reflect.TypeOf((*Bird)(nil)).Elem()
+	obj, err := p.GetServiceContext(context.WithValue(context.Background(), ctxKey{}, "bird"), birdType)
+	if err != nil {
+		t.Error(err)
+	}
+	if bird := (*obj).(*Bird); bird.Name != "bird" {
+		t.Errorf("InitContext is not called")
+	}
+}
+
+func TestInitializeError(t *testing.T) {
+	fail := true
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddServiceHandler[Cat](sc, goioc.Scope, func(provider goioc.IServiceProvider) interface{} {
+		return &Cat{Fail: fail}
+	})
+	p := sc.Build()
+
+	catType := reflect.TypeOf((*Cat)(nil)).Elem()
+	if _, err := p.GetService(catType); err == nil {
+		t.Errorf("Init error is not returned")
+	}
+
+	// 失败的实例不会被缓存
+	fail = false
+	obj, err := p.GetService(catType)
+	if err != nil {
+		t.Error(err)
+	}
+	if cat := (*obj).(*Cat); !cat.Ready {
+		t.Errorf("Scope instance is not recreated")
+	}
+}
+
+func TestFactoryPanicRetry(t *testing.T) {
+	fail := true
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandler[Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		if fail {
+			panic("dependency is not ready")
+		}
+		return &Dog{Id: 1}
+	})
+	p := sc.Build()
+
+	dogType := reflect.TypeOf((*Dog)(nil)).Elem()
+	if _, err := p.GetService(dogType); err == nil {
+		t.Errorf("factory panic is not returned as error")
+	}
+
+	fail = false
+	obj, err := p.GetService(dogType)
+	if err != nil {
+		t.Error(err)
+	}
+	if dog := (*obj).(*Dog); dog.Id != 1 {
+		t.Errorf("singleton is not retried")
+	}
+}
+
+func TestFactoryPanicCacheFailure(t *testing.T) {
+	count := 0
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandler[Dog](sc, goioc.Scope, func(provider goioc.IServiceProvider) interface{} {
+		count++
+		panic("dependency is not ready")
+	})
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{FailurePolicy: goioc.CacheFailure})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dogType := reflect.TypeOf((*Dog)(nil)).Elem()
+	_, err1 := p.GetService(dogType)
+	_, err2 := p.GetService(dogType)
+	if err1 == nil || err1 != err2 {
+		t.Errorf("original error is not cached")
+	}
+	if count != 1 {
+		t.Errorf("factory is called %d times", count)
+	}
+}
+
+// 预热依赖链 Zoo -> Keeper -> IAnimal
+type Keeper struct {
+	Dog IAnimal `ioc:"true"`
+}
+
+type Zoo struct {
+	Keeper *Keeper `ioc:"true"`
+}
+
+func TestWarmup(t *testing.T) {
+	var lock sync.Mutex
+	var order []string
+	record := func(name string) {
+		lock.Lock()
+		defer lock.Unlock()
+		order = append(order, name)
+	}
+
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		record("dog")
+		return &Dog{}
+	})
+	goioc.AddServiceHandler[Keeper](sc, goioc.Transient, func(provider goioc.IServiceProvider) interface{} {
+		return &Keeper{}
+	})
+	goioc.AddServiceHandler[Zoo](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		record("zoo")
+		return &Zoo{}
+	})
+	goioc.AddServiceHandler[Cat](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		record("cat")
+		return &Cat{}
+	})
+
+	_, err := sc.BuildWithOptions(goioc.BuildOptions{EagerSingletons: true, WarmupParallelism: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 3 {
+		t.Fatalf("singletons are not created: %v", order)
+	}
+	for i, name := range order {
+		if name == "zoo" {
+			for _, before := range order[i:] {
+				if before == "dog" {
+					t.Errorf("dependency is created after dependent: %v", order)
+				}
+			}
+		}
+	}
+}
+
+// 顺序预热时依赖先实例化，其余按类型名称排序
+func TestWarmupSequential(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		var order []string
+		sc := &ServiceCollection{}
+		goioc.AddServiceHandler[Animal](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+			order = append(order, "animal")
+			return &Animal{}
+		})
+		goioc.AddServiceHandlerOf[IAnimal, Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+			order = append(order, "dog")
+			return &Dog{}
+		})
+		goioc.AddServiceHandler[Cat](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+			order = append(order, "cat")
+			return &Cat{}
+		})
+		goioc.AddServiceHandler[Bird](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+			order = append(order, "bird")
+			return &Bird{}
+		})
+
+		if _, err := sc.BuildWithOptions(goioc.BuildOptions{EagerSingletons: true}); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(order) != "[dog animal bird cat]" {
+			t.Fatalf("warmup order = %v, expected [dog animal bird cat]", order)
+		}
+	}
+}
+
+// Singleton 经过 Transient 间接依赖 Scope 对象时同样返回错误
+func TestTransitiveCaptive(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Keeper](sc, goioc.Transient)
+	goioc.AddService[Zoo](sc, goioc.Singleton)
+	p := sc.Build()
+
+	if _, err := p.GetService(reflect.TypeOf(Keeper{})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.GetService(reflect.TypeOf(Zoo{})); err == nil || !strings.Contains(err.Error(), "into singleton") {
+		t.Errorf("scope instance should not be captured by singleton: %v", err)
+	}
+}
+
+func TestWarmupError(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandler[Dog](sc, goioc.Singleton, func(provider goioc.IServiceProvider) interface{} {
+		panic("dog")
+	})
+	goioc.AddServiceHandler[Bird](sc, goioc.Scope, func(provider goioc.IServiceProvider) interface{} {
+		panic("bird")
+	})
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{WarmupScoped: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.Warmup(context.Background())
+	var aggregate *goioc.AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 2 {
+		t.Errorf("errors are not aggregated: %v", err)
+	}
+}
+
+func TestCreateScope(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Bird](sc, goioc.Singleton)
+	p := sc.Build()
+	scope := p.CreateScope()
+	defer scope.Dispose()
+
+	if goioc.GetI[IAnimal](p) == goioc.GetI[IAnimal](scope) {
+		t.Errorf("scope instance is shared between scopes")
+	}
+	if goioc.GetS[Bird](p) != goioc.GetS[Bird](scope) {
+		t.Errorf("singleton instance is not shared between scopes")
+	}
+}
+
+// 释放时记录次数
+type Connection struct {
+	Closed int
+}
+
+func (my *Connection) Dispose() { my.Closed++ }
+
+// 依赖没有注册的 IStore
+type Shop struct {
+	Store IStore `ioc:"true"`
+}
+
+func TestHooks(t *testing.T) {
+	var resolving, created, failed []string
+	var scopes, disposed int
+	hooks := goioc.Hooks{
+		OnResolving: func(event goioc.ResolveEvent) {
+			parent := "nil"
+			if event.Parent != nil {
+				parent = event.Parent.Name()
+			}
+			resolving = append(resolving, fmt.Sprintf("%s:%d:%s", event.Type.Name(), event.Depth, parent))
+		},
+		OnCreated: func(event goioc.ResolveEvent, instance interface{}, duration time.Duration) {
+			created = append(created, event.Type.Name())
+		},
+		OnResolveFailed: func(event goioc.ResolveEvent, err error) {
+			failed = append(failed, event.Type.Name())
+		},
+		OnScopeCreated:  func(scope goioc.IServiceProvider) { scopes++ },
+		OnScopeDisposed: func(scope goioc.IServiceProvider) { scopes-- },
+		OnDisposed: func(baseType reflect.Type, instance interface{}) {
+			disposed++
+		},
+	}
+
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Keeper](sc, goioc.Transient)
+	goioc.AddService[Zoo](sc, goioc.Transient)
+	goioc.AddService[Connection](sc, goioc.Scope)
+	goioc.AddService[Shop](sc, goioc.Transient)
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{Hooks: hooks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goioc.GetS[Zoo](p)
+	if fmt.Sprint(resolving) != "[Zoo:0:nil Keeper:1:Zoo IAnimal:2:Keeper]" {
+		t.Errorf("resolving = %v", resolving)
+	}
+	if fmt.Sprint(created) != "[IAnimal Keeper Zoo]" {
+		t.Errorf("created = %v", created)
+	}
+
+	if _, err := p.GetService(reflect.TypeOf(Shop{})); err == nil {
+		t.Fatalf("missing dependency should fail")
+	}
+	if fmt.Sprint(failed) != "[IStore Shop]" {
+		t.Errorf("failed = %v", failed)
+	}
+
+	scope := p.CreateScope()
+	goioc.GetS[Connection](scope)
+	if scopes != 1 {
+		t.Errorf("scopes = %d, expected 1", scopes)
+	}
+	scope.Dispose()
+	if scopes != 0 || disposed != 1 {
+		t.Errorf("scopes = %d, disposed = %d", scopes, disposed)
+	}
+}
+
+// 记录初始化时的 pprof 标签
+type Profiled struct {
+	Keeper *Keeper `ioc:"true"`
+	Label  string
+}
+
+func (my *Profiled) InitContext(ctx context.Context) error {
+	my.Label, _ = pprof.Label(ctx, ServiceLabel)
+	return nil
+}
+
+func TestProfilerLabels(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Transient)
+	goioc.AddService[Keeper](sc, goioc.Transient)
+	goioc.AddService[Profiled](sc, goioc.Transient)
+
+	p, err := sc.BuildWithOptions(goioc.BuildOptions{ProfilerLabels: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiled := goioc.GetS[Profiled](p); profiled.Label != "services.Profiled" {
+		t.Errorf("label = %q, expected services.Profiled", profiled.Label)
+	}
+
+	p = sc.Build()
+	if profiled := goioc.GetS[Profiled](p); profiled.Label != "" {
+		t.Errorf("label = %q, expected no label", profiled.Label)
+	}
+}
+
+func TestSetScopeInstance(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddService[Keeper](sc, goioc.Scope)
+	goioc.AddService[Zoo](sc, goioc.Singleton)
+	p := sc.Build()
+
+	scope := p.CreateScope().(*ServiceProvider)
+	dog := &Dog{Id: 1}
+	if err := scope.SetScopeInstance(reflect.TypeOf((*IAnimal)(nil)).Elem(), dog); err != nil {
+		t.Fatal(err)
+	}
+	if keeper := goioc.GetS[Keeper](scope); keeper.Dog != dog {
+		t.Errorf("scope instance is not injected")
+	}
+	if _, err := p.GetService(reflect.TypeOf((*IAnimal)(nil)).Elem()); err == nil {
+		t.Errorf("scope instance should not be visible in other scopes")
+	}
+	if err := scope.SetScopeInstance(reflect.TypeOf(Zoo{}), &Zoo{}); err == nil {
+		t.Errorf("singleton should not be set as a scope instance")
+	}
+}
+
+func TestFactoryPanicError(t *testing.T) {
+	failure := errors.New("connection refused")
+	sc := &ServiceCollection{}
+	goioc.AddServiceHandler[Bird](sc, goioc.Transient, func(provider goioc.IServiceProvider) interface{} {
+		panic(failure)
+	})
+	p := sc.Build()
+	if _, err := p.GetService(reflect.TypeOf(Bird{})); !errors.Is(err, failure) {
+		t.Errorf("factory error is not wrapped: %v", err)
+	}
+}
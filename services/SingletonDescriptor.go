@@ -4,6 +4,7 @@ import (
 	"github.com/whuanle/goioc"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // SingletonDescriptor 静态对象描述
@@ -11,15 +12,32 @@ type SingletonDescriptor struct {
 	baseType    reflect.Type
 	instance    interface{}
 	initHandler func(provider goioc.IServiceProvider) interface{}
-	lock        *sync.Once
+	// 实例化失败后的处理策略
+	policy goioc.FailurePolicy
+	// 策略为 CacheFailure 时缓存的错误
+	err  error
+	lock *sync.Mutex
+	// 实例是否已经创建，不需要等待正在进行的实例化即可读取
+	created atomic.Bool
 }
 
-// 初始化
-func (descriptor *SingletonDescriptor) initAndGet(provider *ServiceProvider) interface{} {
-	descriptor.lock.Do(func() {
-		if descriptor.instance == nil {
-			descriptor.instance = createObject(provider, descriptor.initHandler(provider), goioc.Singleton)
+// 初始化，r 是对象字段的上下文，实例化失败时根据 policy 决定是否缓存错误
+func (descriptor *SingletonDescriptor) initAndGet(r resolveContext, event goioc.ResolveEvent, provider *ServiceProvider) (interface{}, error) {
+	descriptor.lock.Lock()
+	defer descriptor.lock.Unlock()
+	if descriptor.err != nil {
+		return nil, descriptor.err
+	}
+	if descriptor.instance == nil {
+		instance, err := newInstance(provider, r, event, descriptor.initHandler)
+		if err != nil {
+			if descriptor.policy == goioc.CacheFailure {
+				descriptor.err = err
+			}
+			return nil, err
 		}
-	})
-	return descriptor.instance
+		descriptor.instance = instance
+		descriptor.created.Store(true)
+	}
+	return descriptor.instance, nil
 }
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"github.com/whuanle/goioc"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// RecordSource 注册服务时是否通过 runtime.Caller 记录注册的位置，
+// 记录的位置用于日志、构建错误以及 Describe、ExportGraph 的输出，
+// 大量注册服务且不需要这些信息时可以关闭
+var RecordSource = true
+
+// goioc 模块的包路径，记录位置时跳过模块内部的调用
+var modulePath = reflect.TypeOf(goioc.ServiceDescriptor{}).PkgPath()
+
+// 获取注册服务的位置，即调用栈上第一个不属于 goioc 模块的调用，
+// 格式为 "目录/文件:行号"，RecordSource 为 false 时返回空字符串
+func callerSource() string {
+	if !RecordSource {
+		return ""
+	}
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isModuleFrame(frame) {
+			dir := filepath.Base(filepath.Dir(frame.File))
+			return fmt.Sprintf("%s/%s:%d", dir, filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// 是否为 goioc 模块内部的调用，模块中的测试代码视为外部调用
+func isModuleFrame(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	return strings.HasPrefix(frame.Function, modulePath+".") || strings.HasPrefix(frame.Function, modulePath+"/")
+}
+
+// 在错误中附加注册服务的位置
+func withSource(err error, descriptor goioc.ServiceDescriptor) error {
+	if descriptor.Source == "" {
+		return err
+	}
+	return fmt.Errorf("%w (registered at %s)", err, descriptor.Source)
+}
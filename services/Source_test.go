@@ -0,0 +1,33 @@
+package services
+
+import (
+	"github.com/whuanle/goioc"
+	"strings"
+	"testing"
+)
+
+func TestRecordSource(t *testing.T) {
+	sc := &ServiceCollection{}
+	goioc.AddServiceOf[IAnimal, Dog](sc, goioc.Scope)
+	goioc.AddService[Keeper](sc.When(goioc.OnProfile(goioc.Production)), goioc.Transient)
+	goioc.Configure[DbOptions](sc, "Db")
+	for _, descriptor := range sc.Descriptors() {
+		if !strings.HasPrefix(descriptor.Source, "services/Source_test.go:") {
+			t.Errorf("source of [ %v ] = %q", descriptor.BaseType, descriptor.Source)
+		}
+	}
+
+	goioc.AddService[Animal5](sc, goioc.Transient)
+	_, err := sc.BuildWithOptions(goioc.BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "(registered at services/Source_test.go:") {
+		t.Errorf("build error does not contain the source: %v", err)
+	}
+
+	RecordSource = false
+	defer func() { RecordSource = true }()
+	sc = &ServiceCollection{}
+	goioc.AddService[Keeper](sc, goioc.Transient)
+	if source := sc.Descriptors()[0].Source; source != "" {
+		t.Errorf("source = %q, expected empty", source)
+	}
+}
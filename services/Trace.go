@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// ServiceLabel 启用 BuildOptions.ProfilerLabels 时，
+// 实例化对象期间设置的 pprof 标签，值为正在实例化的类型
+const ServiceLabel = "goioc.service"
+
+// instrument 在 runtime/trace 的 region 中执行 f，
+// labels 为 true 时同时通过 pprof.Do 设置 ServiceLabel 标签，
+// 依赖的对象在 f 中实例化，会得到嵌套的 region 和覆盖外层的标签
+func instrument(ctx context.Context, baseType reflect.Type, labels bool, f func(ctx context.Context)) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	name := baseType.String()
+	run := func(ctx context.Context) {
+		defer trace.StartRegion(ctx, "goioc: "+name).End()
+		f(ctx)
+	}
+	if labels {
+		pprof.Do(ctx, pprof.Labels(ServiceLabel, name), run)
+		return
+	}
+	run(ctx)
+}
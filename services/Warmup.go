@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/whuanle/goioc"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Warmup 按依赖顺序提前实例化所有 Singleton 对象，
+// BuildOptions.WarmupScoped 为 true 时同时实例化当前 Provider 的 Scope 对象，
+// 互不依赖的对象会根据 BuildOptions.WarmupParallelism 并行实例化，
+// WarmupParallelism 不大于 1 时在当前 goroutine 中按依赖关系和类型名称确定的顺序依次实例化。
+func (s *ServiceProvider) Warmup(ctx context.Context) error {
+	s.lock.RLock()
+	descriptors := make(map[reflect.Type]goioc.ServiceDescriptor, len(s.descriptors))
+	for t, descriptor := range s.descriptors {
+		descriptors[t] = descriptor
+	}
+	s.lock.RUnlock()
+
+	targets := map[reflect.Type]bool{}
+	for t, descriptor := range descriptors {
+		if descriptor.Lifetime == goioc.Singleton || (s.options.WarmupScoped && descriptor.Lifetime == goioc.Scope) {
+			targets[t] = true
+		}
+	}
+
+	deps, err := warmupDependencies(descriptors, targets)
+	if err != nil {
+		return err
+	}
+
+	parallelism := s.options.WarmupParallelism
+	if parallelism <= 1 {
+		return s.warmupSequential(ctx, warmupOrder(deps))
+	}
+	semaphore := make(chan struct{}, parallelism)
+	done := make(map[reflect.Type]chan struct{}, len(targets))
+	for t := range targets {
+		done[t] = make(chan struct{})
+	}
+
+	var lock sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, t := range sortedTypes(targets) {
+		wg.Add(1)
+		go func(t reflect.Type) {
+			defer wg.Done()
+			defer close(done[t])
+			// 等待依赖的对象实例化完成，依赖失败时当前对象同样会失败并返回错误
+			for _, dep := range deps[t] {
+				<-done[dep]
+			}
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			err := ctx.Err()
+			if err == nil {
+				_, err = s.GetServiceContext(goioc.AsOwner(ctx), t)
+			}
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("warmup [ %v ]: %w", t, err))
+				lock.Unlock()
+			}
+		}(t)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+// 在当前 goroutine 中按 order 依次实例化，依赖失败时当前对象同样会失败并返回错误
+func (s *ServiceProvider) warmupSequential(ctx context.Context, order []reflect.Type) error {
+	var errs []error
+	for _, t := range order {
+		err := ctx.Err()
+		if err == nil {
+			_, err = s.GetServiceContext(goioc.AsOwner(ctx), t)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("warmup [ %v ]: %w", t, err))
+		}
+	}
+	if len(errs) > 0 {
+		return &goioc.AggregateError{Errors: errs}
+	}
+	return nil
+}
+
+// 按类型名称和依赖关系计算确定的实例化顺序，依赖总是排在被依赖的对象之前
+func warmupOrder(deps map[reflect.Type][]reflect.Type) []reflect.Type {
+	types := make(map[reflect.Type]bool, len(deps))
+	for t := range deps {
+		types[t] = true
+	}
+	visited := map[reflect.Type]bool{}
+	var order []reflect.Type
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for _, dep := range deps[t] {
+			visit(dep)
+		}
+		order = append(order, t)
+	}
+	for _, t := range sortedTypes(types) {
+		visit(t)
+	}
+	return order
+}
+
+// 计算每个需要预热的对象依赖了哪些需要预热的对象，
+// 经过 Transient 等不需要预热的对象间接依赖的也会被计算在内，存在循环依赖时返回错误
+func warmupDependencies(descriptors map[reflect.Type]goioc.ServiceDescriptor, targets map[reflect.Type]bool) (map[reflect.Type][]reflect.Type, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[reflect.Type]int{}
+	// 每个对象能够到达的需要预热的对象
+	reach := map[reflect.Type][]reflect.Type{}
+
+	var visit func(t reflect.Type) error
+	visit = func(t reflect.Type) error {
+		switch state[t] {
+		case visiting:
+			return fmt.Errorf("circular dependency detected at [ %v ]", t)
+		case visited:
+			return nil
+		}
+		state[t] = visiting
+
+		seen := map[reflect.Type]bool{}
+		var result []reflect.Type
+		for _, dep := range dependencies(descriptors[t].ServiceType) {
+			if _, ok := descriptors[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+			if targets[dep] {
+				if !seen[dep] {
+					seen[dep] = true
+					result = append(result, dep)
+				}
+				continue
+			}
+			for _, r := range reach[dep] {
+				if !seen[r] {
+					seen[r] = true
+					result = append(result, r)
+				}
+			}
+		}
+
+		reach[t] = result
+		state[t] = visited
+		return nil
+	}
+
+	deps := map[reflect.Type][]reflect.Type{}
+	for _, t := range sortedTypes(targets) {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+		deps[t] = reach[t]
+	}
+	return deps, nil
+}
+
+// 按类型名称排序，保证计算结果和顺序预热的实例化顺序稳定
+func sortedTypes(types map[reflect.Type]bool) []reflect.Type {
+	result := make([]reflect.Type, 0, len(types))
+	for t := range types {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result
+}